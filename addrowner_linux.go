@@ -0,0 +1,91 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+// addrOwner identifies the process bound to a port by finding the socket's
+// inode in /proc/net and the process holding a descriptor for it. Processes
+// whose descriptors cannot be read are skipped.
+func addrOwner(network string, port int) (pid int, process string) {
+	var tables []string
+	listening := false
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		tables = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+		listening = true
+	case "udp", "udp4", "udp6":
+		tables = []string{"/proc/net/udp", "/proc/net/udp6"}
+	}
+
+	inodes := make(map[string]bool)
+	for _, table := range tables {
+		socketInodes(table, port, listening, inodes)
+	}
+	if len(inodes) == 0 {
+		return 0, ""
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, ""
+	}
+	for _, p := range procs {
+		id, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", p.Name())
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+				return id, strings.TrimSpace(string(comm))
+			}
+		}
+	}
+	return 0, ""
+}
+
+// socketInodes adds to inodes the inode of every socket in a /proc/net table
+// bound to port, considering only listening sockets if listening is set.
+func socketInodes(table string, port int, listening bool, inodes map[string]bool) {
+	data, err := os.ReadFile(table)
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		local := fields[1]
+		i := strings.LastIndexByte(local, ':')
+		p, err := strconv.ParseUint(local[i+1:], 16, 16)
+		if err != nil || int(p) != port {
+			continue
+		}
+		if listening && fields[3] != tcpListen {
+			continue
+		}
+		inodes[fields[9]] = true
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+// addrOwner identifies the process bound to a port. It is only supported on
+// Linux.
+func addrOwner(network string, port int) (pid int, process string) {
+	return 0, ""
+}
@@ -0,0 +1,172 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// errAdminToken is returned by Serve when the admin server is enabled without
+// a token.
+var errAdminToken = errors.New("admin server requires a token")
+
+// WithAdmin serves the admin API on addr, such as "127.0.0.1:9901", for the
+// life of Serve. The listener is opened through Listen, so it survives
+// upgrades. Every request must carry the header "Authorization: Bearer
+// <token>"; Serve fails if token is empty. The API is served over TLS if
+// configured with WithAdminTLS.
+//
+// The admin API provides:
+//
+//	POST /admin/reload  reload as SIGHUP does, returning the applied
+//	                    configuration generation or the reload error; a
+//	                    traceparent header is propagated to ReloadContext
+//	GET  /admin/status  the state, version, configuration generation,
+//	                    components and recent events, as JSON, or as an
+//	                    HTML page for browsers or with ?format=html
+//	GET  /admin/boot    the boot trace, as served by BootHandler
+//
+// An orchestrator can drive a rolling upgrade through the following steps,
+// each answering with a StepResult describing the process that served it:
+//
+//	POST /admin/quiesce  quiesce a lifecycle implementing Quiescer
+//	POST /admin/upgrade  upgrade as SIGUSR2 does, answering once the new
+//	                     process is ready; the old one then stops
+//	POST /admin/verify   check the process is running and ready and, with
+//	                     ?version=, running that version; after an upgrade
+//	                     the new process answers, as it inherits the listener
+//	POST /admin/resume   resume a quiesced lifecycle, such as after a failed
+//	                     upgrade or verification
+func WithAdmin(addr, token string) Option {
+	return func(d *Dissembler) {
+		d.adminAddr = addr
+		d.adminToken = token
+	}
+}
+
+// adminShutdownTimeout bounds how long closing the admin server waits for
+// responses in flight, such as to an upgrade step.
+const adminShutdownTimeout = 5 * time.Second
+
+// serveAdmin starts the admin server, returning it so Serve can close it.
+func (d *Dissembler) serveAdmin() (*http.Server, error) {
+	if d.adminToken == "" {
+		return nil, errAdminToken
+	}
+	config, err := d.adminTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := Listen("tcp", d.adminAddr)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+	d.adminPolicy = d.tlsPolicy(config)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/reload", d.adminReload)
+	mux.HandleFunc("/admin/status", d.adminStatus)
+	mux.Handle("/admin/boot", BootHandler())
+	mux.HandleFunc("/admin/quiesce", d.adminStep("quiesce", d.adminQuiesce))
+	mux.HandleFunc("/admin/upgrade", d.adminStep("upgrade", d.adminUpgrade))
+	mux.HandleFunc("/admin/verify", d.adminStep("verify", d.adminVerify))
+	mux.HandleFunc("/admin/resume", d.adminStep("resume", d.adminResume))
+	srv := &http.Server{Handler: d.adminAuth(mux)}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			msgUnableToServeAdminAPI.emit(
+				log.String("error", err.Error()),
+			)
+		}
+	}()
+	return srv, nil
+}
+
+// closeAdmin closes the admin server once responses in flight are written.
+func closeAdmin(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+	}
+}
+
+// adminAuth refuses requests not bearing the admin token.
+func (d *Dissembler) adminAuth(next http.Handler) http.Handler {
+	want := []byte("Bearer " + d.adminToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dissembler"`)
+			writeAdmin(w, http.StatusUnauthorized, adminResponse{
+				Error: http.StatusText(http.StatusUnauthorized),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminResponse is the body of an admin API response.
+type adminResponse struct {
+	Generation uint64 `json:"generation,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// adminReload reloads through the same serialized path as SIGHUP.
+func (d *Dissembler) adminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAdmin(w, http.StatusMethodNotAllowed, adminResponse{
+			Error: http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	// The reload outlives the request's context, so only its traceparent is
+	// carried over.
+	ctx, err := withTraceParent(context.Background(), r.Header.Get("traceparent"))
+	if err != nil {
+		writeAdmin(w, http.StatusBadRequest, adminResponse{Error: err.Error()})
+		return
+	}
+
+	msgAdminReloadRequested.emit(
+		log.String("remote", r.RemoteAddr),
+		log.String("traceparent", r.Header.Get("traceparent")),
+	)
+	err = d.request(ctx, syscall.SIGHUP)
+	switch {
+	case err == nil:
+		writeAdmin(w, http.StatusOK, adminResponse{Generation: d.Generation()})
+	case errors.Is(err, ErrReloadUnsupported):
+		writeAdmin(w, http.StatusNotImplemented, adminResponse{Error: err.Error()})
+	case errors.Is(err, errNotServing):
+		writeAdmin(w, http.StatusServiceUnavailable, adminResponse{Error: err.Error()})
+	default:
+		writeAdmin(w, http.StatusUnprocessableEntity, adminResponse{
+			Generation: d.Generation(),
+			Error:      err.Error(),
+		})
+	}
+}
+
+// writeAdmin writes an admin API response.
+func writeAdmin(w http.ResponseWriter, code int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// errAdminTLS is returned by Serve when approved cryptography is required
+// without the admin server using TLS.
+var errAdminTLS = errors.New("approved cryptography requires admin TLS")
+
+// approvedCipherSuites are the TLS 1.2 cipher suites allowed by
+// WithApprovedCrypto: ECDHE key exchange with AES-GCM, as approved by
+// NIST SP 800-52r2 and FIPS 140-3.
+var approvedCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// approvedCurves are the key exchange groups allowed by WithApprovedCrypto.
+var approvedCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// WithAdminTLS serves the admin API over TLS with config, which must carry
+// the server certificate.
+func WithAdminTLS(config *tls.Config) Option {
+	return func(d *Dissembler) {
+		d.adminTLS = config
+	}
+}
+
+// WithApprovedCrypto restricts the admin server's TLS to approved versions,
+// cipher suites and key exchange groups for regulated environments: TLS 1.2
+// with ECDHE and AES-GCM, and the NIST curves. TLS 1.3 is allowed only when
+// the binary runs in FIPS 140-3 mode (GODEBUG=fips140=on), since crypto/tls
+// otherwise offers TLS 1.3 cipher suites that cannot be restricted. The
+// active policy is shown on the admin status page. Serve fails if the admin
+// server does not use TLS.
+func WithApprovedCrypto() Option {
+	return func(d *Dissembler) {
+		d.approvedCrypto = true
+	}
+}
+
+// TLSPolicy describes the admin server's TLS configuration.
+type TLSPolicy struct {
+	// Approved reports whether WithApprovedCrypto restricts the policy.
+	Approved bool `json:"approved"`
+	// FIPS140 reports whether the binary runs in FIPS 140-3 mode.
+	FIPS140      bool     `json:"fips140"`
+	MinVersion   string   `json:"min_version"`
+	MaxVersion   string   `json:"max_version"`
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+	Curves       []string `json:"curves,omitempty"`
+}
+
+// adminTLSConfig returns the admin server's TLS configuration, with the
+// approved policy applied if required, or nil if it does not use TLS.
+func (d *Dissembler) adminTLSConfig() (*tls.Config, error) {
+	if d.adminTLS == nil {
+		if d.approvedCrypto {
+			return nil, errAdminTLS
+		}
+		return nil, nil
+	}
+	config := d.adminTLS.Clone()
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if !d.approvedCrypto {
+		return config, nil
+	}
+
+	config.MinVersion = tls.VersionTLS12
+	config.MaxVersion = tls.VersionTLS12
+	if fipsEnabled() {
+		config.MaxVersion = tls.VersionTLS13
+	}
+	config.CipherSuites = approvedCipherSuites
+	config.CurvePreferences = approvedCurves
+	return config, nil
+}
+
+// tlsPolicy describes config for the admin status page.
+func (d *Dissembler) tlsPolicy(config *tls.Config) *TLSPolicy {
+	if config == nil {
+		return nil
+	}
+	p := &TLSPolicy{
+		Approved:   d.approvedCrypto,
+		FIPS140:    fipsEnabled(),
+		MinVersion: tls.VersionName(config.MinVersion),
+		MaxVersion: tls.VersionName(tls.VersionTLS13),
+	}
+	if config.MaxVersion != 0 {
+		p.MaxVersion = tls.VersionName(config.MaxVersion)
+	}
+	for _, id := range config.CipherSuites {
+		p.CipherSuites = append(p.CipherSuites, tls.CipherSuiteName(id))
+	}
+	for _, c := range config.CurvePreferences {
+		p.Curves = append(p.Curves, c.String())
+	}
+	return p
+}
@@ -0,0 +1,79 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"runtime"
+
+	log "github.com/uber-go/zap"
+)
+
+// errSchedUnsupported is returned when CPU affinity or niceness is requested
+// on a platform where it cannot be applied.
+var errSchedUnsupported = errors.New("CPU affinity and niceness are not supported on " + runtime.GOOS)
+
+// WithCPUAffinity pins every thread of the process to the given CPUs and sets
+// GOMAXPROCS to their number, so latency-sensitive daemons neither migrate
+// between nor schedule goroutines across more CPUs than they may run on. The
+// affinity is applied and logged when Serve begins, and exported in Metrics
+// as "cpu_affinity". It is supported on Linux; elsewhere Serve fails.
+func WithCPUAffinity(cpus ...int) Option {
+	return func(d *Dissembler) {
+		d.cpus = cpus
+	}
+}
+
+// WithNice sets the niceness of every thread of the process, from -20 (most
+// favourable scheduling) to 19 (least), when Serve begins. Lowering niceness
+// requires privileges. It is supported on Linux; elsewhere Serve fails.
+func WithNice(n int) Option {
+	return func(d *Dissembler) {
+		d.nice = n
+		d.renice = true
+	}
+}
+
+// pinCPUs applies the configured niceness and CPU affinity, if any.
+func (d *Dissembler) pinCPUs() error {
+	if d.renice {
+		if d.nice < -20 || d.nice > 19 {
+			return fmt.Errorf("niceness %d out of range -20 to 19", d.nice)
+		}
+		if err := setNice(d.nice); err != nil {
+			return fmt.Errorf("setting niceness: %w", err)
+		}
+		msgNicenessSet.emit(
+			log.Int("nice", d.nice),
+		)
+	}
+
+	if len(d.cpus) == 0 {
+		return nil
+	}
+	for _, cpu := range d.cpus {
+		if cpu < 0 {
+			return fmt.Errorf("invalid CPU %d", cpu)
+		}
+	}
+	if err := setAffinity(d.cpus); err != nil {
+		return fmt.Errorf("setting CPU affinity: %w", err)
+	}
+	runtime.GOMAXPROCS(len(d.cpus))
+
+	msgCPUAffinityPinned.emit(
+		log.String("cpus", fmt.Sprint(d.cpus)),
+		log.Int("gomaxprocs", runtime.GOMAXPROCS(0)),
+	)
+	cpus := append([]int(nil), d.cpus...)
+	Metrics.Set(metricCPUAffinity, expvar.Func(func() interface{} {
+		return map[string]interface{}{
+			"cpus":       cpus,
+			"gomaxprocs": runtime.GOMAXPROCS(0),
+		}
+	}))
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// setAffinity pins every thread of the process to cpus.
+func setAffinity(cpus []int) error {
+	max := 0
+	for _, cpu := range cpus {
+		if cpu > max {
+			max = cpu
+		}
+	}
+	mask := make([]uint64, max/64+1)
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+
+	return eachThread(func(tid int) error {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+			uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// setNice sets the niceness of every thread of the process.
+func setNice(n int) error {
+	return eachThread(func(tid int) error {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, tid, n)
+	})
+}
+
+// eachThread calls fn for every thread of the process. Scheduling attributes
+// are per thread on Linux and new threads inherit them from the thread that
+// creates them, so the threads are listed until a pass finds none not yet
+// seen, covering threads created by the runtime meanwhile.
+func eachThread(fn func(tid int) error) error {
+	seen := make(map[int]bool)
+	for {
+		tasks, err := os.ReadDir("/proc/self/task")
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, task := range tasks {
+			tid, err := strconv.Atoi(task.Name())
+			if err != nil || seen[tid] {
+				continue
+			}
+			seen[tid] = true
+			found = true
+			if err := fn(tid); err != nil && err != syscall.ESRCH {
+				return err
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+}
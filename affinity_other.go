@@ -0,0 +1,17 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+// setAffinity is not supported outside Linux.
+func setAffinity(cpus []int) error {
+	return errSchedUnsupported
+}
+
+// setNice is not supported outside Linux.
+func setNice(n int) error {
+	return errSchedUnsupported
+}
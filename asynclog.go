@@ -0,0 +1,45 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import "sync"
+
+// asyncLogBuffer is the number of log calls that may be queued before logAsync
+// falls back to logging synchronously.
+const asyncLogBuffer = 64
+
+var (
+	asyncLogs     = make(chan func(), asyncLogBuffer)
+	asyncLogsOnce sync.Once
+)
+
+// logAsync queues fn, a logging call, to run on a background goroutine so
+// latency-sensitive paths such as signal handling do not wait on the log's
+// writer. Queued calls run in order. If the queue is full, fn runs
+// synchronously rather than being dropped.
+func logAsync(fn func()) {
+	asyncLogsOnce.Do(startAsyncLogs)
+	select {
+	case asyncLogs <- fn:
+	default:
+		fn()
+	}
+}
+
+// flushLogs waits for the calls queued by logAsync to run.
+func flushLogs() {
+	asyncLogsOnce.Do(startAsyncLogs)
+	done := make(chan struct{})
+	asyncLogs <- func() { close(done) }
+	<-done
+}
+
+// startAsyncLogs starts the goroutine running queued log calls.
+func startAsyncLogs() {
+	go func() {
+		for fn := range asyncLogs {
+			fn()
+		}
+	}()
+}
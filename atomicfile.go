@@ -0,0 +1,68 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to the file at path so readers see either the
+// previous contents or the new contents in full, never a partial write, even
+// across a crash or power loss. See WriteFileAtomicFunc.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomicFunc(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFileAtomicFunc writes the file at path with the contents written by
+// fn, for state files such as snapshots that are streamed rather than held in
+// memory. The contents are written to a temporary file in the same directory,
+// which is given mode perm regardless of the umask, synced to disk and renamed
+// over path, and the directory is then synced so the rename is durable. If fn
+// or any step fails, path is left unchanged and the temporary file removed.
+func WriteFileAtomicFunc(path string, perm os.FileMode, fn func(w io.Writer) error) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = fn(f); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	// The file is in place; a failure to sync the directory only risks the
+	// rename being lost in a crash, so it is not undone.
+	d, derr := os.Open(dir)
+	if derr != nil {
+		return derr
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,122 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// DefaultBarrierTimeout bounds how long stopping waits for the processes
+// given to WithStopOrder when no timeout is given.
+const DefaultBarrierTimeout = 30 * time.Second
+
+// barrierPoll is how often a process stopped before this one is checked.
+const barrierPoll = 100 * time.Millisecond
+
+// stopBarrier orders the stopping of co-located processes.
+type stopBarrier struct {
+	dir     string
+	name    string
+	after   []string
+	timeout time.Duration
+	lock    *os.File
+}
+
+// WithStopOrder coordinates the shutdown of processes on one host, such as an
+// application and the sidecar cache it depends on, through lock files in dir
+// shared by them. The process registers as name, holding dir/name.lock from
+// before Init until it has stopped, and when stopping first waits, up to
+// timeout (DefaultBarrierTimeout if zero), for each process named in after to
+// have stopped. A process that is not running, or has died, does not delay
+// the stop.
+//
+// For example, a cache stopping only once the application has:
+//
+//	dissembler.WithStopOrder("/run/dissembler", "cache", 0, "app")
+func WithStopOrder(dir, name string, timeout time.Duration, after ...string) Option {
+	return func(d *Dissembler) {
+		if timeout <= 0 {
+			timeout = DefaultBarrierTimeout
+		}
+		d.barrier = &stopBarrier{dir: dir, name: name, after: after, timeout: timeout}
+	}
+}
+
+// join registers the process with the barrier by taking its lock.
+func (b *stopBarrier) join() error {
+	if b == nil {
+		return nil
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(b.lockPath(b.name), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return &os.PathError{Op: "lock", Path: f.Name(), Err: err}
+	}
+	b.lock = f
+	return nil
+}
+
+// leave releases the process's lock, letting processes waiting on it stop.
+func (b *stopBarrier) leave() {
+	if b == nil || b.lock == nil {
+		return
+	}
+	b.lock.Close()
+	b.lock = nil
+}
+
+// await waits for the processes to stop before this one to have stopped.
+func (b *stopBarrier) await(clock Clock) {
+	if b == nil {
+		return
+	}
+	deadline := clock.Now().Add(b.timeout)
+	for _, name := range b.after {
+		start := clock.Now()
+		for !b.stopped(name) {
+			if !clock.Now().Before(deadline) {
+				msgUnableToAwaitProcess.emit(
+					log.String("process", name),
+					log.Duration("timeout", b.timeout),
+				)
+				return
+			}
+			<-clock.After(barrierPoll)
+		}
+		msgAwaitedProcess.emit(
+			log.String("process", name),
+			log.Duration("waited", clock.Now().Sub(start)),
+		)
+	}
+}
+
+// stopped reports whether the named process is not running.
+func (b *stopBarrier) stopped(name string) bool {
+	f, err := os.Open(b.lockPath(name))
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return false
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return true
+}
+
+// lockPath returns the path of the named process's lock file.
+func (b *stopBarrier) lockPath(name string) string {
+	return filepath.Join(b.dir, name+".lock")
+}
@@ -0,0 +1,169 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// bootReadyPoll is how often readiness is polled while booting.
+const bootReadyPoll = 100 * time.Millisecond
+
+// boot is the trace of this process's boot.
+var boot = &bootTrace{begin: time.Now()}
+
+// BootHandler returns a handler serving the boot trace as a flame-style
+// report, for the application to mount where it sees fit, such as at
+// /debug/boot on an internal server. The admin API serves it at
+// /admin/boot.
+func BootHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		boot.WriteTo(w)
+	})
+}
+
+// bootStep is a timed step of the boot.
+type bootStep struct {
+	name  string
+	depth int
+	start time.Time
+	end   time.Time
+}
+
+// bootTrace records the steps of the boot, from package initialization until
+// the lifecycle is started and ready.
+type bootTrace struct {
+	mu       sync.Mutex
+	begin    time.Time
+	steps    []*bootStep
+	open     []*bootStep
+	complete time.Time
+}
+
+// BootStep records a named step of the boot, such as a cache warmup, and
+// returns a function that ends it. Steps begun while another step is in
+// progress are nested within it. Steps begun after the boot is complete are
+// not recorded. Dissembler records the Init of the lifecycle and of each Group
+// component, and the time until the lifecycle is ready.
+//
+// The boot trace is logged as steps end and served as a flame-style report by
+// BootHandler.
+func BootStep(name string) (end func()) {
+	return boot.step(name)
+}
+
+// step opens a step and returns the function that ends it. Once the boot is
+// complete, steps are no longer recorded.
+func (t *bootTrace) step(name string) func() {
+	t.mu.Lock()
+	if !t.complete.IsZero() {
+		t.mu.Unlock()
+		return func() {}
+	}
+	s := &bootStep{name: name, depth: len(t.open), start: time.Now()}
+	t.steps = append(t.steps, s)
+	t.open = append(t.open, s)
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.end(s) })
+	}
+}
+
+// end closes a step.
+func (t *bootTrace) end(s *bootStep) {
+	t.mu.Lock()
+	s.end = time.Now()
+	for i, o := range t.open {
+		if o == s {
+			t.open = append(t.open[:i], t.open[i+1:]...)
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	msgBootStepComplete.emit(
+		log.String("step", s.name),
+		log.Duration("duration", s.end.Sub(s.start)),
+	)
+}
+
+// finish marks the boot complete and logs its total duration.
+func (t *bootTrace) finish() {
+	t.mu.Lock()
+	if !t.complete.IsZero() {
+		t.mu.Unlock()
+		return
+	}
+	t.complete = time.Now()
+	elapsed := t.complete.Sub(t.begin)
+	t.mu.Unlock()
+
+	msgBootComplete.emit(
+		log.Duration("duration", elapsed))
+}
+
+// WriteTo writes the boot trace as a flame-style report: one line per step,
+// indented by nesting, with a bar positioned and sized by when the step began
+// and how long it took relative to the whole boot.
+func (t *bootTrace) WriteTo(w io.Writer) (int64, error) {
+	const width = 50
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	end := t.complete
+	if end.IsZero() {
+		end = now
+	}
+	total := end.Sub(t.begin)
+	if total <= 0 {
+		total = 1
+	}
+
+	var b strings.Builder
+	state := "complete"
+	if t.complete.IsZero() {
+		state = "in progress"
+	}
+	fmt.Fprintf(&b, "boot %s: %s\n\n", state, total)
+	fmt.Fprintf(&b, "%-40s %10s %10s\n", "STEP", "OFFSET", "DURATION")
+	for _, s := range t.steps {
+		stop := s.end
+		if stop.IsZero() {
+			stop = now
+		}
+		offset, took := s.start.Sub(t.begin), stop.Sub(s.start)
+
+		pad := int(int64(width) * int64(offset) / int64(total))
+		bar := int(int64(width) * int64(took) / int64(total))
+		if bar < 1 {
+			bar = 1
+		}
+		if pad+bar > width {
+			bar = width - pad
+		}
+
+		name := strings.Repeat("  ", s.depth) + s.name
+		if s.end.IsZero() {
+			name += " …"
+		}
+		fmt.Fprintf(&b, "%-40s %10s %10s |%s%s\n", name,
+			offset.Round(time.Millisecond), took.Round(time.Millisecond),
+			strings.Repeat(" ", pad), strings.Repeat("█", bar))
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
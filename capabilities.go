@@ -0,0 +1,99 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// Capability is a Linux capability.
+type Capability int
+
+// Capabilities commonly retained by daemons. Other capabilities may be given
+// by number, as defined in linux/capability.h.
+const (
+	CapChown          Capability = 0
+	CapDacOverride    Capability = 1
+	CapDacReadSearch  Capability = 2
+	CapFowner         Capability = 3
+	CapKill           Capability = 5
+	CapSetgid         Capability = 6
+	CapSetuid         Capability = 7
+	CapNetBindService Capability = 10
+	CapNetAdmin       Capability = 12
+	CapNetRaw         Capability = 13
+	CapIPCLock        Capability = 14
+	CapSysChroot      Capability = 18
+	CapSysPtrace      Capability = 19
+	CapSysAdmin       Capability = 21
+	CapSysNice        Capability = 23
+	CapSysResource    Capability = 24
+)
+
+// capabilityNames are the names of the capabilities defined above.
+var capabilityNames = map[Capability]string{
+	CapChown:          "CAP_CHOWN",
+	CapDacOverride:    "CAP_DAC_OVERRIDE",
+	CapDacReadSearch:  "CAP_DAC_READ_SEARCH",
+	CapFowner:         "CAP_FOWNER",
+	CapKill:           "CAP_KILL",
+	CapSetgid:         "CAP_SETGID",
+	CapSetuid:         "CAP_SETUID",
+	CapNetBindService: "CAP_NET_BIND_SERVICE",
+	CapNetAdmin:       "CAP_NET_ADMIN",
+	CapNetRaw:         "CAP_NET_RAW",
+	CapIPCLock:        "CAP_IPC_LOCK",
+	CapSysChroot:      "CAP_SYS_CHROOT",
+	CapSysPtrace:      "CAP_SYS_PTRACE",
+	CapSysAdmin:       "CAP_SYS_ADMIN",
+	CapSysNice:        "CAP_SYS_NICE",
+	CapSysResource:    "CAP_SYS_RESOURCE",
+}
+
+// String returns the name of the capability, such as CAP_NET_BIND_SERVICE.
+func (c Capability) String() string {
+	if name, ok := capabilityNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Capability(%d)", int(c))
+}
+
+// WithKeepCapabilities drops every capability but caps once Init has
+// returned, so Init may bind privileged ports and open protected files while
+// the running process keeps only what it needs, such as CapNetBindService to
+// rebind on reload. The capabilities are removed from the effective,
+// permitted, inheritable and bounding sets, and the result is verified and
+// logged; Serve fails if they cannot be dropped.
+//
+// Capabilities exist only on Linux, and belong to each thread, so like
+// WithUnshare this requires a binary built without cgo.
+func WithKeepCapabilities(caps ...Capability) Option {
+	return func(d *Dissembler) {
+		d.dropCaps = true
+		d.keepCaps = append(d.keepCaps, caps...)
+	}
+}
+
+// dropCapabilities applies WithKeepCapabilities.
+func (d *Dissembler) dropCapabilities() error {
+	if !d.dropCaps {
+		return nil
+	}
+	kept, err := keepCapabilities(d.keepCaps)
+	if err != nil {
+		return fmt.Errorf("dropping capabilities: %w", err)
+	}
+
+	names := make([]string, len(kept))
+	for i, c := range kept {
+		names[i] = c.String()
+	}
+	msgCapabilitiesDropped.emit(
+		log.String("kept", strings.Join(names, ",")),
+	)
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// linuxCapabilityVersion3 selects 64-bit capability sets.
+	linuxCapabilityVersion3 = 0x20080522
+	// prCapbsetDrop is PR_CAPBSET_DROP.
+	prCapbsetDrop = 24
+	// prSetNoNewPrivs is PR_SET_NO_NEW_PRIVS.
+	prSetNoNewPrivs = 38
+)
+
+// capHeader and capData are the arguments of capget and capset.
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+// keepCapabilities drops every capability but caps from every thread,
+// returning the effective capabilities then held by the calling thread.
+func keepCapabilities(caps []Capability) ([]Capability, error) {
+	last, err := lastCapability()
+	if err != nil {
+		return nil, err
+	}
+
+	var mask [2]uint32
+	for _, c := range caps {
+		if c < 0 || c > last {
+			return nil, fmt.Errorf("unknown capability %d", int(c))
+		}
+		mask[c/32] |= 1 << uint(c%32)
+	}
+
+	// The bounding set is lowered first, since doing so requires
+	// CAP_SETPCAP, which is about to be dropped unless kept.
+	for c := Capability(0); c <= last; c++ {
+		if mask[c/32]&(1<<uint(c%32)) != 0 {
+			continue
+		}
+		if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL,
+			prCapbsetDrop, uintptr(c), 0); errno != 0 {
+			return nil, allThreadsError("prctl", errno)
+		}
+	}
+
+	hdr := capHeader{version: linuxCapabilityVersion3}
+	var data [2]capData
+	for i := range data {
+		data[i] = capData{effective: mask[i], permitted: mask[i], inheritable: mask[i]}
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CAPSET,
+		uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return nil, allThreadsError("capset", errno)
+	}
+
+	// Verify the result.
+	hdr = capHeader{version: linuxCapabilityVersion3}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPGET,
+		uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return nil, os.NewSyscallError("capget", errno)
+	}
+	var kept []Capability
+	for c := Capability(0); c <= last; c++ {
+		bit := uint32(1) << uint(c%32)
+		effective := data[c/32].effective&bit != 0
+		if effective != (mask[c/32]&bit != 0) {
+			if effective {
+				return nil, fmt.Errorf("%s still held", c)
+			}
+			return nil, fmt.Errorf("%s not held, so cannot be kept", c)
+		}
+		if effective {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// lastCapability returns the highest capability the kernel supports.
+func lastCapability() (Capability, error) {
+	b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || n < 0 || n > 63 {
+		return 0, errors.New("invalid /proc/sys/kernel/cap_last_cap")
+	}
+	return Capability(n), nil
+}
+
+// allThreadsError describes a failure of AllThreadsSyscall.
+func allThreadsError(call string, errno syscall.Errno) error {
+	if errno == syscall.ENOTSUP {
+		return fmt.Errorf("%s: not supported in binaries using cgo", call)
+	}
+	return os.NewSyscallError(call, errno)
+}
@@ -0,0 +1,14 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import "errors"
+
+// keepCapabilities is not supported outside Linux.
+func keepCapabilities(caps []Capability) ([]Capability, error) {
+	return nil, errors.New("capabilities require Linux")
+}
@@ -0,0 +1,159 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	log "github.com/uber-go/zap"
+)
+
+// CertReloader holds a certificate and key, and optionally a pool of client
+// CAs, loaded from files and reloaded on demand, so TLS listeners pick up
+// renewed certificates without restarting. Reload keeps the current
+// certificates if the files cannot be loaded. Registered with
+// WithCertReloader, certificates are reloaded on every reload of the
+// lifecycle, such as on SIGHUP.
+type CertReloader struct {
+	// certFile and keyFile are the PEM encoded certificate chain and key,
+	// and clientCAFile, if set, the PEM encoded CAs verifying client
+	// certificates. They are fixed by NewCertReloader.
+	certFile, keyFile, clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// errNoClientCAs is returned to a handshake requiring verified client
+// certificates from a CertReloader without client CAs.
+var errNoClientCAs = errors.New("client certificate verification requires client CAs")
+
+// NewCertReloader returns a CertReloader loading the certificate and key from
+// certFile and keyFile, and, unless clientCAFile is empty, the CAs verifying
+// client certificates from clientCAFile.
+func NewCertReloader(certFile, keyFile, clientCAFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload loads the certificates from their files again.
+func (c *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if c.clientCAFile != "" {
+		pem, err := os.ReadFile(c.clientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no certificates found", c.clientCAFile)
+		}
+	}
+
+	c.mu.Lock()
+	c.cert, c.clientCAs = &cert, pool
+	c.mu.Unlock()
+	return nil
+}
+
+// GetCertificate returns the current certificate, for use as
+// tls.Config.GetCertificate.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return c.cert, nil
+}
+
+// Config returns a TLS configuration serving the current certificate and
+// authenticating clients according to clientAuth against the current client
+// CAs, so each listener may have its own client certificate policy:
+//
+//	certs, err := dissembler.NewCertReloader("tls.crt", "tls.key", "clients.pem")
+//	...
+//	public, err := dissembler.Listen("tcp", ":443", dissembler.TLS(certs.Config(tls.NoClientCert)))
+//	internal, err := dissembler.Listen("tcp", ":8443", dissembler.TLS(certs.Config(tls.RequireAndVerifyClientCert)))
+//
+// Handshakes fail if clientAuth verifies client certificates and the
+// CertReloader has no client CAs, rather than verifying them against the
+// system roots.
+func (c *CertReloader) Config(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: clientAuth,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+			if c.cert == nil {
+				return nil, errors.New("no certificate loaded")
+			}
+			if clientAuth >= tls.VerifyClientCertIfGiven && c.clientCAs == nil {
+				return nil, errNoClientCAs
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*c.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    c.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// WithCertReloader reloads the certificates of every CertReloader given each
+// time the lifecycle is reloaded, before the lifecycle itself. If they cannot
+// be reloaded, the reload fails and the current certificates are kept.
+func WithCertReloader(certs ...*CertReloader) Option {
+	return func(d *Dissembler) {
+		d.certs = append(d.certs, certs...)
+	}
+}
+
+// reloadCerts reloads the certificates registered with WithCertReloader.
+func (d *Dissembler) reloadCerts() error {
+	for _, c := range d.certs {
+		if err := c.Reload(); err != nil {
+			msgUnableToReloadCerts.emit(
+				log.String("cert", c.certFile),
+				log.String("error", err.Error()),
+			)
+			return err
+		}
+	}
+	return nil
+}
+
+// TLS makes the listener terminate TLS with config on every accepted
+// connection, after reading any PROXY header, so plain TCP components gain
+// TLS, and the certificate reloading of a CertReloader, without handling it
+// themselves. The handshake happens on the connection's first use.
+func TLS(config *tls.Config) ListenOption {
+	return func(c *listenConfig) {
+		c.tls = config
+	}
+}
+
+// serveTLS wraps an accepted connection to terminate TLS if TLS is set.
+func (c *listenConfig) serveTLS(conn net.Conn) net.Conn {
+	if c.tls == nil {
+		return conn
+	}
+	return tls.Server(conn, c.tls)
+}
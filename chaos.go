@@ -0,0 +1,101 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// ErrChaosReload is the error returned by a Reload failure injected by chaos
+// mode.
+var ErrChaosReload = errors.New("chaos: injected reload failure")
+
+// Chaos configures failure injection, for testing how deployment automation
+// copes with a misbehaving service. Chaos mode is off unless enabled with
+// WithChaos, and must never be enabled in production.
+type Chaos struct {
+	// Seed seeds the random source, so a run can be reproduced.
+	Seed int64
+	// StartDelay is the longest artificial delay before Start is called. Each
+	// start is delayed by a random duration up to StartDelay.
+	StartDelay time.Duration
+	// ReloadFailure is the probability, from 0 to 1, that a reload fails with
+	// ErrChaosReload without calling Reload.
+	ReloadFailure float64
+	// StopHang is how long a simulated hang delays Stop.
+	StopHang time.Duration
+	// StopHangRate is the probability, from 0 to 1, that Stop hangs.
+	StopHangRate float64
+}
+
+// WithChaos enables chaos mode, injecting the failures configured by c.
+func WithChaos(c Chaos) Option {
+	return func(d *Dissembler) {
+		d.chaos = &chaos{Chaos: c, rand: rand.New(rand.NewSource(c.Seed))}
+	}
+}
+
+// chaos injects failures according to its configuration. A nil *chaos
+// injects nothing.
+type chaos struct {
+	Chaos
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// announce logs that chaos mode is enabled, so a run with injected failures
+// is never mistaken for a healthy one.
+func (c *chaos) announce() {
+	if c == nil {
+		return
+	}
+	msgChaosModeEnabled.emit(
+		log.Int64("seed", c.Seed),
+	)
+}
+
+// float64 returns a random number in [0, 1).
+func (c *chaos) float64() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rand.Float64()
+}
+
+// delayStart waits a random duration up to StartDelay.
+func (c *chaos) delayStart(clock Clock) {
+	if c == nil || c.StartDelay <= 0 {
+		return
+	}
+	delay := time.Duration(c.float64() * float64(c.StartDelay))
+	msgChaosDelayingStart.emit(
+		log.Duration("delay", delay),
+	)
+	<-clock.After(delay)
+}
+
+// failReload returns ErrChaosReload with probability ReloadFailure.
+func (c *chaos) failReload() error {
+	if c == nil || c.ReloadFailure <= 0 || c.float64() >= c.ReloadFailure {
+		return nil
+	}
+	msgChaosFailingReload.emit()
+	return ErrChaosReload
+}
+
+// hangStop waits StopHang with probability StopHangRate.
+func (c *chaos) hangStop(clock Clock) {
+	if c == nil || c.StopHang <= 0 || c.float64() >= c.StopHangRate {
+		return
+	}
+	msgChaosHangingStop.emit(
+		log.Duration("hang", c.StopHang),
+	)
+	<-clock.After(c.StopHang)
+}
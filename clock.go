@@ -0,0 +1,95 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for Dissembler's timeouts, backoffs and tickers.
+// The system clock is used unless another is supplied with WithClock or the
+// Clock field of a Group, Supervisor or HTTPServer; tests supply a fake clock,
+// such as dissemblertest.FakeClock, to advance time deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a Timer that fires once after the duration.
+	NewTimer(d time.Duration) Timer
+	// NewTicker creates a Ticker that fires every period.
+	NewTicker(period time.Duration) Ticker
+}
+
+// Timer is a single event created by a Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing, reporting whether it was active.
+	Stop() bool
+}
+
+// Ticker delivers ticks at intervals from a Clock.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the Ticker.
+	Stop()
+}
+
+// WithClock sets the Clock used for Dissembler's own timeouts and tickers.
+func WithClock(c Clock) Option {
+	return func(d *Dissembler) {
+		d.clock = c
+	}
+}
+
+// clockOr returns c, or the system clock if c is nil.
+func clockOr(c Clock) Clock {
+	if c == nil {
+		return systemClock{}
+	}
+	return c
+}
+
+// withTimeout is context.WithTimeout measured on the Clock c.
+func withTimeout(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t := c.NewTimer(d)
+	go func() {
+		defer t.Stop()
+		select {
+		case <-t.C():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// systemClock is the Clock backed by package time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+// systemTimer adapts a time.Timer to Timer.
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+// systemTicker adapts a time.Ticker to Ticker.
+type systemTicker struct{ t *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
@@ -0,0 +1,206 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// cloudDetectTimeout bounds the detection of the cloud environment. Metadata
+// endpoints answer within milliseconds on their cloud and not at all
+// elsewhere.
+const cloudDetectTimeout = time.Second
+
+// errNoCloud is returned when no cloud metadata endpoint answered.
+var errNoCloud = errors.New("no cloud metadata endpoint answered")
+
+// Instance identifies the cloud instance a process runs on.
+type Instance struct {
+	// Provider is "aws", "gcp" or "azure".
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Region   string `json:"region,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+}
+
+// cloudInstance holds the detected *Instance.
+var cloudInstance atomic.Value
+
+// CloudInstance returns the cloud instance detected by WithCloudMetadata,
+// reporting false if none was detected.
+func CloudInstance() (Instance, bool) {
+	i, ok := cloudInstance.Load().(*Instance)
+	if !ok {
+		return Instance{}, false
+	}
+	return *i, true
+}
+
+// WithCloudMetadata detects, before Init, whether the process runs on EC2,
+// GCE or Azure by querying their instance metadata endpoints, and enriches
+// logs, events and the admin status with the instance ID, region and zone.
+// Detection is bounded to a second; off-cloud it finds nothing and Serve
+// proceeds without enrichment.
+func WithCloudMetadata() Option {
+	return func(d *Dissembler) {
+		d.cloudMetadata = true
+	}
+}
+
+// detectCloud detects the cloud instance if enabled by WithCloudMetadata.
+func (d *Dissembler) detectCloud() {
+	if !d.cloudMetadata {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cloudDetectTimeout)
+	defer cancel()
+
+	i, err := detectInstance(ctx)
+	if err != nil {
+		msgNoCloudInstanceDetected.emit(
+			log.String("error", err.Error()),
+		)
+		return
+	}
+	cloudInstance.Store(&i)
+	SetLogger(Logger().With(
+		log.String("cloud_provider", i.Provider),
+		log.String("instance_id", i.ID),
+		log.String("region", i.Region),
+		log.String("zone", i.Zone),
+	))
+	msgCloudInstanceDetected.emit()
+}
+
+// detectInstance queries every provider's metadata endpoint at once,
+// returning the first instance found.
+func detectInstance(ctx context.Context) (Instance, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	probes := []func(context.Context) (Instance, error){probeEC2, probeGCE, probeAzure}
+	found := make(chan Instance, len(probes))
+	failed := make(chan struct{}, len(probes))
+	for _, probe := range probes {
+		go func(probe func(context.Context) (Instance, error)) {
+			i, err := probe(ctx)
+			if err != nil {
+				failed <- struct{}{}
+				return
+			}
+			found <- i
+		}(probe)
+	}
+
+	for range probes {
+		select {
+		case i := <-found:
+			return i, nil
+		case <-failed:
+		}
+	}
+	return Instance{}, errNoCloud
+}
+
+// probeEC2 reads the EC2 instance identity document using IMDSv2.
+func probeEC2(ctx context.Context) (Instance, error) {
+	token, err := metadata(ctx, http.MethodPut, "http://169.254.169.254/latest/api/token",
+		"X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if err != nil {
+		return Instance{}, err
+	}
+	doc, err := metadata(ctx, http.MethodGet,
+		"http://169.254.169.254/latest/dynamic/instance-identity/document",
+		"X-aws-ec2-metadata-token", string(token))
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var v struct {
+		InstanceID       string `json:"instanceId"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+	}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return Instance{}, err
+	}
+	return Instance{Provider: "aws", ID: v.InstanceID, Region: v.Region, Zone: v.AvailabilityZone}, nil
+}
+
+// probeGCE reads the GCE instance metadata.
+func probeGCE(ctx context.Context) (Instance, error) {
+	doc, err := metadata(ctx, http.MethodGet,
+		"http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true",
+		"Metadata-Flavor", "Google")
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var v struct {
+		ID   json.Number `json:"id"`
+		Zone string      `json:"zone"`
+	}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return Instance{}, err
+	}
+	// The zone is given as projects/<number>/zones/<zone>, and the region is
+	// the zone without its final letter, as in us-central1-a.
+	zone := v.Zone[strings.LastIndex(v.Zone, "/")+1:]
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return Instance{Provider: "gcp", ID: v.ID.String(), Region: region, Zone: zone}, nil
+}
+
+// probeAzure reads the Azure instance metadata.
+func probeAzure(ctx context.Context) (Instance, error) {
+	doc, err := metadata(ctx, http.MethodGet,
+		"http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01",
+		"Metadata", "true")
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var v struct {
+		VMID     string `json:"vmId"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return Instance{}, err
+	}
+	return Instance{Provider: "azure", ID: v.VMID, Region: v.Location, Zone: v.Zone}, nil
+}
+
+// metadata requests url from a metadata endpoint with the given header.
+func metadata(ctx context.Context, method, url, header, value string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(header, value)
+
+	// Metadata endpoints must be reached directly, never through a proxy.
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
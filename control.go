@@ -0,0 +1,262 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+const (
+	// maxControlLine is the longest command accepted on the control socket,
+	// including the terminating newline.
+	maxControlLine = 1024
+	// maxControlTokens is the most words a command may have.
+	maxControlTokens = 16
+	// maxControlWord is the longest command or flag name.
+	maxControlWord = 32
+	// controlTimeout bounds how long a control connection may take to send
+	// its command and receive the response.
+	controlTimeout = 5 * time.Second
+)
+
+var (
+	errNotServing      = errors.New("not serving")
+	errControlEmpty    = errors.New("empty command")
+	errControlTooLong  = fmt.Errorf("command longer than %d bytes", maxControlLine)
+	errControlTooMany  = fmt.Errorf("command has more than %d words", maxControlTokens)
+	errControlBadWord  = errors.New("names must be lowercase letters, digits and dashes")
+	errControlDupFlag  = errors.New("duplicate flag")
+	errControlTrailing = errors.New("data after command")
+)
+
+// WithControlSocket serves the control protocol on a Unix socket at path,
+// letting operators and tooling reload, stop or query the process without
+// signals. The socket is created with mode 0600 and handed to the new process
+// on upgrade.
+//
+// The protocol is line oriented: a client sends a single command, such as
+// "reload" or "stop", terminated by a newline, and receives "ok" or
+// "error: <reason>" on the first line of the response, followed by any
+// output, after which the connection is closed. Commands take positional
+// arguments and flags of the form --name=value or --name value; a W3C
+// traceparent given with --traceparent is propagated to the lifecycle.
+func WithControlSocket(path string) Option {
+	return func(d *Dissembler) {
+		d.controlPath = path
+	}
+}
+
+// controlCommand is a parsed control command.
+type controlCommand struct {
+	Name  string
+	Args  []string
+	Flags map[string]string
+}
+
+// String encodes the command in the control protocol, without the newline.
+func (c controlCommand) String() string {
+	words := append([]string{c.Name}, c.Args...)
+	names := make([]string, 0, len(c.Flags))
+	for name := range c.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		words = append(words, "--"+name+"="+c.Flags[name])
+	}
+	return strings.Join(words, " ")
+}
+
+// parseControl parses a single command line. Commands are limited to
+// maxControlLine bytes of printable ASCII in at most maxControlTokens words,
+// so malformed or hostile input is rejected without allocating in proportion
+// to it.
+func parseControl(line []byte) (controlCommand, error) {
+	if len(line) > maxControlLine {
+		return controlCommand{}, errControlTooLong
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	for _, b := range line {
+		if b < 0x20 || b > 0x7e {
+			return controlCommand{}, fmt.Errorf("invalid byte %#x in command", b)
+		}
+	}
+
+	words := strings.Fields(string(line))
+	switch {
+	case len(words) == 0:
+		return controlCommand{}, errControlEmpty
+	case len(words) > maxControlTokens:
+		return controlCommand{}, errControlTooMany
+	case !isControlWord(words[0]):
+		return controlCommand{}, fmt.Errorf("command %q: %w", words[0], errControlBadWord)
+	}
+
+	cmd := controlCommand{Name: words[0]}
+	for i := 1; i < len(words); i++ {
+		w := words[i]
+		if !strings.HasPrefix(w, "--") {
+			cmd.Args = append(cmd.Args, w)
+			continue
+		}
+
+		name, value, ok := strings.Cut(w[2:], "=")
+		if !isControlWord(name) {
+			return controlCommand{}, fmt.Errorf("flag %q: %w", name, errControlBadWord)
+		}
+		if !ok && i+1 < len(words) && !strings.HasPrefix(words[i+1], "--") {
+			value = words[i+1]
+			i++
+		}
+		if _, dup := cmd.Flags[name]; dup {
+			return controlCommand{}, fmt.Errorf("flag %q: %w", name, errControlDupFlag)
+		}
+		if cmd.Flags == nil {
+			cmd.Flags = make(map[string]string)
+		}
+		cmd.Flags[name] = value
+	}
+	return cmd, nil
+}
+
+// isControlWord reports whether s is a valid command or flag name.
+func isControlWord(s string) bool {
+	if s == "" || len(s) > maxControlWord || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// listenControl opens the control socket at path through Listen, removing a
+// stale socket left behind by a process that did not exit cleanly.
+func listenControl(path string) (net.Listener, error) {
+	ln, err := Listen("unix", path)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if conn, derr := net.Dial("unix", path); derr == nil {
+			conn.Close()
+			return nil, err
+		}
+		os.Remove(path)
+		ln, err = Listen("unix", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// serveControl accepts control connections until ln is closed.
+func (d *Dissembler) serveControl(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		go d.handleControl(conn)
+	}
+}
+
+// handleControl reads one command from conn, runs it and writes the response.
+func (d *Dissembler) handleControl(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	r := bufio.NewReaderSize(io.LimitReader(conn, maxControlLine+1), maxControlLine+1)
+	line, err := r.ReadSlice('\n')
+	if err == nil && r.Buffered() > 0 {
+		err = errControlTrailing
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if errors.Is(err, bufio.ErrBufferFull) || (err == io.EOF && len(line) > maxControlLine) {
+		err = errControlTooLong
+	}
+
+	var out string
+	if err == nil {
+		var cmd controlCommand
+		if cmd, err = parseControl(line); err == nil {
+			msgControlCommandReceived.emit(
+				log.String("command", cmd.String()))
+			out, err = d.control(cmd)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(conn, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(conn, "ok")
+	if out != "" {
+		io.WriteString(conn, strings.TrimSuffix(out, "\n")+"\n")
+	}
+}
+
+// control runs a control command, returning its output. Any command may carry
+// a --traceparent flag, which is propagated to the lifecycle's ReloadContext
+// or StopContext.
+func (d *Dissembler) control(cmd controlCommand) (string, error) {
+	ctx, err := withTraceParent(context.Background(), cmd.Flags["traceparent"])
+	if err != nil {
+		return "", err
+	}
+
+	switch cmd.Name {
+	case "ping":
+		return "pong", nil
+	case "reload":
+		return "", d.request(ctx, syscall.SIGHUP)
+	case "stop":
+		_, at := cmd.Flags["at"]
+		_, cancel := cmd.Flags["cancel"]
+		if at || cancel {
+			return d.controlStop(cmd.Flags)
+		}
+		return "", d.request(ctx, syscall.SIGTERM)
+	case "upgrade":
+		return "", d.request(ctx, syscall.SIGUSR2)
+	case "activate":
+		if !d.standby {
+			return "", errors.New("not in standby")
+		}
+		d.Activate()
+		return "", nil
+	case "rehearse-shutdown":
+		return d.rehearse(), nil
+	case "signals":
+		return d.signalTable(), nil
+	case "describe":
+		return d.describe()
+	}
+	return "", fmt.Errorf("unknown command %q", cmd.Name)
+}
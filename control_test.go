@@ -0,0 +1,41 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"reflect"
+	"testing"
+)
+
+// FuzzParseControl checks that every command that parses encodes back to a
+// line that parses to the same command.
+func FuzzParseControl(f *testing.F) {
+	for _, seed := range []string{
+		"ping\n",
+		"reload --traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\n",
+		"stop --at 02:00 --lead 10m\n",
+		"stop --cancel\n",
+		"upgrade\r\n",
+		"rehearse-shutdown\n",
+		"describe extra args\n",
+		"stop --at=2017-01-02T15:04:05Z --at=again\n",
+		"RELOAD\n",
+		"\n",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cmd, err := parseControl(data)
+		if err != nil {
+			return
+		}
+		again, err := parseControl([]byte(cmd.String()))
+		if err != nil {
+			t.Fatalf("re-parsing %q: %v", cmd.String(), err)
+		}
+		if !reflect.DeepEqual(cmd, again) {
+			t.Fatalf("round trip changed %#v to %#v", cmd, again)
+		}
+	})
+}
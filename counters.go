@@ -0,0 +1,109 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// envCounters carries the monotonic counters of a process over to the process
+// replacing it through an upgrade.
+const envCounters = "DISSEMBLER_COUNTERS"
+
+// signalCounterPrefix prefixes the signal statistics in envCounters.
+const signalCounterPrefix = "signal."
+
+// Restarts returns how many times the lifecycle has been handed over to a new
+// process through an upgrade, counting from the process first started.
+func (d *Dissembler) Restarts() uint64 {
+	return atomic.LoadUint64(&d.restarts)
+}
+
+// exportCounters exports the restart and signal counters in Metrics. Like
+// the signal statistics, they carry over to the process replacing the current
+// one through an upgrade, so dashboards do not show them reset on every
+// zero-downtime deploy.
+func (d *Dissembler) exportCounters() {
+	Metrics.Set(metricRestarts, expvar.Func(func() interface{} {
+		return d.Restarts()
+	}))
+	Metrics.Set(metricSignalsTotal, expvar.Func(func() interface{} {
+		var n uint64
+		for _, s := range d.SignalStats() {
+			n += s.Received
+		}
+		return n
+	}))
+}
+
+// encodeCounters returns the counters to carry over to the process replacing
+// the current one, counting the restart.
+func (d *Dissembler) encodeCounters() string {
+	v := url.Values{}
+	v.Set(metricRestarts, strconv.FormatUint(d.Restarts()+1, 10))
+	for _, s := range d.SignalStats() {
+		v.Set(signalCounterPrefix+s.Signal,
+			fmt.Sprintf("%d,%d,%d", s.Received, s.Ignored, s.Last.UnixNano()))
+	}
+	return v.Encode()
+}
+
+// restoreCounters restores the counters carried over by the process this one
+// replaced through an upgrade, if any.
+func (d *Dissembler) restoreCounters() {
+	s, ok := os.LookupEnv(envCounters)
+	if !ok {
+		return
+	}
+	os.Unsetenv(envCounters)
+
+	v, err := url.ParseQuery(s)
+	if err == nil {
+		var restarts uint64
+		restarts, err = strconv.ParseUint(v.Get(metricRestarts), 10, 64)
+		atomic.StoreUint64(&d.restarts, restarts)
+	}
+	if err != nil {
+		msgUnableToRestoreCounters.emit(
+			log.String("error", err.Error()),
+		)
+		return
+	}
+
+	d.sigMu.Lock()
+	defer d.sigMu.Unlock()
+	for k := range v {
+		name := strings.TrimPrefix(k, signalCounterPrefix)
+		if name == k {
+			continue
+		}
+		var received, ignored uint64
+		var last int64
+		if _, err := fmt.Sscanf(v.Get(k), "%d,%d,%d", &received, &ignored, &last); err != nil {
+			msgUnableToRestoreCounters.emit(
+				log.String("signal", name),
+				log.String("error", err.Error()),
+			)
+			continue
+		}
+		if d.sigStats == nil {
+			d.sigStats = make(map[string]*SignalStat)
+		}
+		d.sigStats[name] = &SignalStat{
+			Signal:   name,
+			Received: received,
+			Ignored:  ignored,
+			Last:     time.Unix(0, last),
+		}
+	}
+}
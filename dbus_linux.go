@@ -0,0 +1,377 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// systemBus is the address of the D-Bus system bus.
+const systemBus = "/run/dbus/system_bus_socket"
+
+// D-Bus message types.
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// D-Bus header fields.
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSignature   = 8
+	dbusFieldUnixFDs     = 9
+)
+
+// dbusConn is a minimal D-Bus client, sufficient to call methods taking
+// string arguments and to receive signals and file descriptors. It avoids
+// depending on a D-Bus library for the few logind calls Dissembler makes.
+type dbusConn struct {
+	conn   *net.UnixConn
+	serial uint32
+	buf    []byte
+	fds    []int
+}
+
+// dbusMessage is a received D-Bus message.
+type dbusMessage struct {
+	typ         byte
+	iface       string
+	member      string
+	errorName   string
+	replySerial uint32
+	signature   string
+	body        []byte
+	fds         []int
+}
+
+// dialSystemBus connects and authenticates to the system bus.
+func dialSystemBus() (*dbusConn, error) {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: systemBus, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	c := &dbusConn{conn: conn}
+	if err := c.auth(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus",
+		"org.freedesktop.DBus", "Hello"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// auth performs SASL EXTERNAL authentication, negotiating file descriptor
+// passing.
+func (c *dbusConn) auth() error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := c.conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		return err
+	}
+	// The reader is discarded after authentication, so it must not read
+	// ahead of the last line the server sends before BEGIN.
+	r := bufio.NewReaderSize(c.conn, 16)
+	line, err := readAuthLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("D-Bus authentication rejected: %s", line)
+	}
+	if _, err := c.conn.Write([]byte("NEGOTIATE_UNIX_FD\r\n")); err != nil {
+		return err
+	}
+	if line, err = readAuthLine(r); err != nil {
+		return err
+	}
+	if line != "AGREE_UNIX_FD" {
+		return fmt.Errorf("D-Bus file descriptor passing refused: %s", line)
+	}
+	_, err = c.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+// readAuthLine reads one line of the authentication exchange a byte at a
+// time, so nothing beyond it is consumed.
+func readAuthLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, b)
+	}
+}
+
+// call calls a method taking string arguments and waits for its reply,
+// discarding any other messages meanwhile.
+func (c *dbusConn) call(dest, path, iface, member string, args ...string) (*dbusMessage, error) {
+	c.serial++
+	serial := c.serial
+	if _, err := c.conn.Write(encodeCall(serial, dest, path, iface, member, args)); err != nil {
+		return nil, err
+	}
+
+	for {
+		m, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if m.replySerial != serial {
+			closeFDs(m.fds)
+			continue
+		}
+		if m.typ == dbusError {
+			closeFDs(m.fds)
+			return nil, fmt.Errorf("%s.%s: %s", iface, member, m.errorName)
+		}
+		return m, nil
+	}
+}
+
+// read reads the next message.
+func (c *dbusConn) read() (*dbusMessage, error) {
+	for {
+		if m, n, err := decodeMessage(c.buf); err != nil {
+			return nil, err
+		} else if m != nil {
+			c.buf = c.buf[n:]
+			nfds := len(m.fds)
+			if nfds > len(c.fds) {
+				return nil, errors.New("D-Bus message missing file descriptors")
+			}
+			m.fds, c.fds = c.fds[:nfds:nfds], c.fds[nfds:]
+			return m, nil
+		}
+
+		buf := make([]byte, 4096)
+		oob := make([]byte, syscall.CmsgSpace(16*4))
+		n, oobn, _, _, err := c.conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, buf[:n]...)
+
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			fds, err := syscall.ParseUnixRights(&msg)
+			if err == nil {
+				c.fds = append(c.fds, fds...)
+			}
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *dbusConn) Close() error {
+	closeFDs(c.fds)
+	return c.conn.Close()
+}
+
+// closeFDs closes file descriptors received but not used.
+func closeFDs(fds []int) {
+	for _, fd := range fds {
+		syscall.Close(fd)
+	}
+}
+
+// encodeCall encodes a method call with string arguments.
+func encodeCall(serial uint32, dest, path, iface, member string, args []string) []byte {
+	var body []byte
+	for _, a := range args {
+		body = appendString(body, a)
+	}
+
+	var fields []byte
+	field := func(code byte, sig, value string) {
+		fields = pad(fields, 8)
+		fields = append(fields, code, 1, sig[0], 0)
+		if sig == "g" {
+			fields = append(fields, byte(len(value)))
+			fields = append(fields, value...)
+			fields = append(fields, 0)
+			return
+		}
+		fields = appendString(fields, value)
+	}
+	field(dbusFieldPath, "o", path)
+	field(dbusFieldDestination, "s", dest)
+	field(dbusFieldInterface, "s", iface)
+	field(dbusFieldMember, "s", member)
+	if len(args) > 0 {
+		field(dbusFieldSignature, "g", strings.Repeat("s", len(args)))
+	}
+
+	// The header fields array starts at offset 16, which is 8-aligned, so
+	// alignment within it matches alignment within the message.
+	msg := []byte{'l', dbusMethodCall, 0, 1}
+	msg = binary.LittleEndian.AppendUint32(msg, uint32(len(body)))
+	msg = binary.LittleEndian.AppendUint32(msg, serial)
+	msg = binary.LittleEndian.AppendUint32(msg, uint32(len(fields)))
+	msg = append(msg, fields...)
+	msg = pad(msg, 8)
+	return append(msg, body...)
+}
+
+// appendString appends a D-Bus string or object path.
+func appendString(b []byte, s string) []byte {
+	b = pad(b, 4)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+// pad pads b with zeros to a multiple of n bytes.
+func pad(b []byte, n int) []byte {
+	for len(b)%n != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// decodeMessage decodes the little- or big-endian message at the start of b,
+// returning nil if b does not yet hold all of it. Only the header fields
+// Dissembler uses are decoded.
+func decodeMessage(b []byte) (*dbusMessage, int, error) {
+	if len(b) < 16 {
+		return nil, 0, nil
+	}
+	var order binary.ByteOrder
+	switch b[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid D-Bus byte order %q", b[0])
+	}
+
+	bodyLen := int(order.Uint32(b[4:]))
+	fieldsLen := int(order.Uint32(b[12:]))
+	headerLen := 16 + fieldsLen
+	headerLen += (8 - headerLen%8) % 8
+	if bodyLen > 1<<27 || fieldsLen > 1<<26 {
+		return nil, 0, errors.New("D-Bus message too large")
+	}
+	if len(b) < headerLen+bodyLen {
+		return nil, 0, nil
+	}
+
+	m := &dbusMessage{typ: b[1], body: b[headerLen : headerLen+bodyLen]}
+	d := dbusDecoder{b: b[:16+fieldsLen], pos: 16, order: order}
+	for d.pos < len(d.b) {
+		d.align(8)
+		code := d.byte()
+		sig := d.signature()
+		switch sig {
+		case "s", "o":
+			v := d.string()
+			switch code {
+			case dbusFieldInterface:
+				m.iface = v
+			case dbusFieldMember:
+				m.member = v
+			case dbusFieldErrorName:
+				m.errorName = v
+			}
+		case "g":
+			if v := d.signature(); code == dbusFieldSignature {
+				m.signature = v
+			}
+		case "u":
+			v := d.uint32()
+			switch code {
+			case dbusFieldReplySerial:
+				m.replySerial = v
+			case dbusFieldUnixFDs:
+				m.fds = make([]int, v)
+			}
+		default:
+			return nil, 0, fmt.Errorf("unexpected D-Bus header field type %q", sig)
+		}
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+	}
+	return m, headerLen + bodyLen, nil
+}
+
+// dbusDecoder reads D-Bus values from a message.
+type dbusDecoder struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+func (d *dbusDecoder) align(n int) {
+	d.pos += (n - d.pos%n) % n
+}
+
+func (d *dbusDecoder) need(n int) bool {
+	if d.err == nil && d.pos+n > len(d.b) {
+		d.err = errors.New("truncated D-Bus message")
+	}
+	return d.err == nil
+}
+
+func (d *dbusDecoder) byte() byte {
+	if !d.need(1) {
+		return 0
+	}
+	d.pos++
+	return d.b[d.pos-1]
+}
+
+func (d *dbusDecoder) uint32() uint32 {
+	d.align(4)
+	if !d.need(4) {
+		return 0
+	}
+	d.pos += 4
+	return d.order.Uint32(d.b[d.pos-4:])
+}
+
+func (d *dbusDecoder) string() string {
+	n := int(d.uint32())
+	if !d.need(n + 1) {
+		return ""
+	}
+	d.pos += n + 1
+	return string(d.b[d.pos-n-1 : d.pos-1])
+}
+
+func (d *dbusDecoder) signature() string {
+	n := int(d.byte())
+	if !d.need(n + 1) {
+		return ""
+	}
+	d.pos += n + 1
+	return string(d.b[d.pos-n-1 : d.pos-1])
+}
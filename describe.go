@@ -0,0 +1,210 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Description is a machine-readable description of the running assembly: its
+// components and the optional interfaces they implement, how each signal is
+// handled, the timeouts in effect and the endpoints served.
+type Description struct {
+	Version    string                   `json:"version"`
+	Components []ComponentDescription   `json:"components"`
+	Signals    []SignalDescription      `json:"signals"`
+	Timeouts   map[string]time.Duration `json:"timeouts"`
+	Endpoints  []EndpointDescription    `json:"endpoints,omitempty"`
+}
+
+// ComponentDescription describes the lifecycle, or one component of it.
+type ComponentDescription struct {
+	// Name names the component within a Group. It is empty for the
+	// lifecycle itself.
+	Name string `json:"name,omitempty"`
+	// Type is the Go type of the component.
+	Type string `json:"type"`
+	// Optional is set for components added with Optional.
+	Optional bool `json:"optional,omitempty"`
+	// Interfaces are the optional interfaces the component implements, such
+	// as "Reloader" or "ReadyChecker".
+	Interfaces []string `json:"interfaces,omitempty"`
+}
+
+// SignalDescription describes how a signal is handled.
+type SignalDescription struct {
+	Signal string `json:"signal"`
+	// Action is what the signal does, such as "reload", or "ignored".
+	Action string `json:"action"`
+	// Budget bounds how long a stop by the signal may take, as set by
+	// WithStopBudget.
+	Budget time.Duration `json:"budget,omitempty"`
+}
+
+// EndpointDescription describes a listener opened through Listen or
+// ListenPacket, or a socket served by Dissembler itself.
+type EndpointDescription struct {
+	Name    string `json:"name,omitempty"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	TLS     bool   `json:"tls,omitempty"`
+	Proxy   bool   `json:"proxy,omitempty"`
+
+	key string
+}
+
+// optionalInterfaces are the optional interfaces reported by Describe.
+var optionalInterfaces = []struct {
+	name       string
+	implements func(Lifecycle) bool
+}{
+	{"Reloader", func(lc Lifecycle) bool { _, ok := lc.(Reloader); return ok }},
+	{"ContextReloader", func(lc Lifecycle) bool { _, ok := lc.(ContextReloader); return ok }},
+	{"ContextStopper", func(lc Lifecycle) bool { _, ok := lc.(ContextStopper); return ok }},
+	{"ReadyChecker", func(lc Lifecycle) bool { _, ok := lc.(ReadyChecker); return ok }},
+	{"Quiescer", func(lc Lifecycle) bool { _, ok := lc.(Quiescer); return ok }},
+	{"Rollbacker", func(lc Lifecycle) bool { _, ok := lc.(Rollbacker); return ok }},
+	{"DrainReporter", func(lc Lifecycle) bool { _, ok := lc.(DrainReporter); return ok }},
+	{"ShutdownRehearser", func(lc Lifecycle) bool { _, ok := lc.(ShutdownRehearser); return ok }},
+	{"WorkCounter", func(lc Lifecycle) bool { _, ok := lc.(WorkCounter); return ok }},
+	{"StatusReporter", func(lc Lifecycle) bool { _, ok := lc.(StatusReporter); return ok }},
+}
+
+// Describe describes the running assembly, for ops tooling. It backs the
+// describe control command, which prints it as JSON.
+func (d *Dissembler) Describe() Description {
+	desc := Description{
+		Version:    fullVersion(),
+		Components: describeComponents(d.lifecycle),
+		Timeouts: map[string]time.Duration{
+			"upgrade":         UpgradeTimeout,
+			"goroutine_grace": GoroutineGrace,
+		},
+		Endpoints: listeners.endpoints(),
+	}
+
+	reload := "reload"
+	if !canReload(d.lifecycle) {
+		reload = "ignored"
+	}
+	desc.Signals = []SignalDescription{
+		{Signal: syscall.SIGHUP.String(), Action: reload},
+		{Signal: syscall.SIGUSR1.String(), Action: "ignored"},
+		{Signal: syscall.SIGUSR2.String(), Action: "upgrade"},
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM} {
+		desc.Signals = append(desc.Signals, SignalDescription{
+			Signal: sig.String(),
+			Action: "stop",
+			Budget: d.stopBudgets[sig],
+		})
+	}
+
+	for name, t := range map[string]time.Duration{
+		"reload_interval": d.reloadInterval,
+		"reload_bake":     d.bake,
+		"drain_lead":      d.drainLead,
+	} {
+		if t > 0 {
+			desc.Timeouts[name] = t
+		}
+	}
+
+	for i, e := range desc.Endpoints {
+		switch {
+		case e.Name != "":
+		case e.key == key("unix", d.controlPath):
+			desc.Endpoints[i].Name = "control"
+		case e.key == key("tcp", d.adminAddr):
+			desc.Endpoints[i].Name = "admin"
+			desc.Endpoints[i].TLS = d.adminPolicy != nil
+		}
+	}
+	return desc
+}
+
+// describeComponents describes lc and, if it is a Group, its components.
+func describeComponents(lc Lifecycle) []ComponentDescription {
+	c := ComponentDescription{Type: fmt.Sprintf("%T", lc)}
+	for _, i := range optionalInterfaces {
+		if i.implements(lc) {
+			c.Interfaces = append(c.Interfaces, i.name)
+		}
+	}
+	descs := []ComponentDescription{c}
+	if g, ok := lc.(*Group); ok {
+		descs = append(descs, g.describe()...)
+	}
+	return descs
+}
+
+// describe describes every component of the Group, in the order Start starts
+// them.
+func (g *Group) describe() []ComponentDescription {
+	g.mu.Lock()
+	components := append([]*component(nil), g.components...)
+	g.mu.Unlock()
+
+	var descs []ComponentDescription
+	for _, c := range components {
+		for i, desc := range describeComponents(c.lc) {
+			if desc.Name == "" {
+				desc.Name = c.name
+				desc.Optional = i == 0 && c.optional
+			} else {
+				desc.Name = c.name + "/" + desc.Name
+			}
+			descs = append(descs, desc)
+		}
+	}
+	return descs
+}
+
+// endpoints describes the active listeners, ordered by network and address.
+func (r *registry) endpoints() []EndpointDescription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]string, 0, len(r.active))
+	for k := range r.active {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	descs := make([]EndpointDescription, 0, len(keys))
+	for _, k := range keys {
+		network, address, _ := strings.Cut(k, "|")
+		switch f := r.active[k].(type) {
+		case net.Listener:
+			address = f.Addr().String()
+		case net.PacketConn:
+			address = f.LocalAddr().String()
+		}
+		c := r.active[k].config()
+		descs = append(descs, EndpointDescription{
+			Name:    c.name,
+			Network: network,
+			Address: address,
+			TLS:     c.tls != nil,
+			Proxy:   c.proxyTimeout > 0,
+			key:     k,
+		})
+	}
+	return descs
+}
+
+// describe returns the description of the assembly as indented JSON.
+func (d *Dissembler) describe() (string, error) {
+	b, err := json.MarshalIndent(d.Describe(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
@@ -0,0 +1,170 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// DefaultDiskInterval is how often free space is checked when no interval is
+// given to WatchDisk.
+const DefaultDiskInterval = 30 * time.Second
+
+// DiskThreshold declares the free space below which a path is low or critical.
+type DiskThreshold struct {
+	// Path is a path on the filesystem watched, such as a data or log
+	// directory.
+	Path string
+	// Low is the free space, in bytes, below which a warning is logged.
+	Low int64
+	// Critical is the free space, in bytes, below which an error is logged and
+	// the OnDiskCritical hooks run.
+	Critical int64
+}
+
+// DiskLevel is how low free space is relative to a DiskThreshold.
+type DiskLevel int
+
+const (
+	// DiskOK is free space at or above the Low threshold.
+	DiskOK DiskLevel = iota
+	// DiskLow is free space below the Low threshold.
+	DiskLow
+	// DiskCritical is free space below the Critical threshold.
+	DiskCritical
+)
+
+// String returns the name of the disk level.
+func (l DiskLevel) String() string {
+	switch l {
+	case DiskOK:
+		return "ok"
+	case DiskLow:
+		return "low"
+	case DiskCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("DiskLevel(%d)", int(l))
+}
+
+// WatchDisk checks the free space on each threshold's path every interval so
+// the process degrades predictably rather than failing on ENOSPC. When a
+// path's level changes, it is logged and an EventDisk published; when a path
+// becomes critical, the OnDiskCritical hooks run. Free space is exported in
+// Metrics as "disk_free".
+func WatchDisk(interval time.Duration, thresholds ...DiskThreshold) Option {
+	return func(d *Dissembler) {
+		if interval <= 0 {
+			interval = DefaultDiskInterval
+		}
+		d.diskInterval = interval
+		d.disks = append(d.disks, thresholds...)
+	}
+}
+
+// OnDiskCritical registers a named hook, such as truncating logs or entering
+// a maintenance mode that refuses writes, to run each time a path watched by
+// WatchDisk becomes critical. Hooks run in order of registration, each within
+// its own budget.
+func OnDiskCritical(name string, fn func(ctx context.Context) error, opts ...HookOption) Option {
+	return func(d *Dissembler) {
+		d.diskHooks = append(d.diskHooks, newHook(name, fn, opts))
+	}
+}
+
+// diskFree returns the free space available to unprivileged users on the
+// filesystem holding path.
+func diskFree(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(uint64(st.Bavail) * uint64(st.Bsize)), nil
+}
+
+// watchDisk watches free space if enabled by WatchDisk, until Serve returns.
+func (d *Dissembler) watchDisk() {
+	if len(d.disks) == 0 {
+		return
+	}
+	free := new(expvar.Map).Init()
+	Metrics.Set(metricDiskFree, free)
+
+	levels := make([]DiskLevel, len(d.disks))
+	check := func() {
+		for i, t := range d.disks {
+			n, err := diskFree(t.Path)
+			if err != nil {
+				msgUnableToCheckDisk.emit(
+					log.String("path", t.Path),
+					log.String("error", err.Error()),
+				)
+				continue
+			}
+			v := new(expvar.Int)
+			v.Set(n)
+			free.Set(t.Path, v)
+
+			level := DiskOK
+			switch {
+			case n < t.Critical:
+				level = DiskCritical
+			case n < t.Low:
+				level = DiskLow
+			}
+			if level != levels[i] {
+				levels[i] = level
+				d.diskLevelChanged(t.Path, level, n)
+			}
+		}
+	}
+
+	check()
+	go func() {
+		clock := clockOr(d.clock)
+		for {
+			select {
+			case <-clock.After(d.diskInterval):
+				check()
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+}
+
+// diskLevelChanged reports that free space on path changed level.
+func (d *Dissembler) diskLevelChanged(path string, level DiskLevel, free int64) {
+	fields := []log.Field{
+		log.String("path", path),
+		log.String("free", formatBytes(free)),
+	}
+	switch level {
+	case DiskOK:
+		msgDiskSpaceRecovered.emit(fields...)
+	case DiskLow:
+		msgDiskSpaceLow.emit(fields...)
+	case DiskCritical:
+		msgDiskSpaceCritical.emit(fields...)
+	}
+	publish(Event{
+		Type:      EventDisk,
+		Time:      clockOr(d.clock).Now(),
+		Path:      path,
+		DiskLevel: level,
+		DiskFree:  free,
+	})
+
+	if level == DiskCritical {
+		for _, h := range d.diskHooks {
+			d.runHook(msgDiskHookFailed, h)
+		}
+	}
+}
@@ -5,9 +5,17 @@
 package dissembler
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	log "github.com/uber-go/zap"
 )
@@ -37,8 +45,6 @@ const (
 var (
 	// Registered is the currently registered Dissembler.
 	Registered Lifecycle
-	// DissemblerLogger is
-	DissemblerLogger log.Logger
 )
 
 // Lifecycle is the lifecycle of a represented API, service, or application.
@@ -63,15 +69,222 @@ type Reloader interface {
 	Reload() error
 }
 
+// FailureNotifier is an optional interface that may be implemented by a
+// Lifecycle whose work can fail after its Start has returned, such as a Group
+// running components added later. A failure received from Failures fails the
+// lifecycle as an error returned by Start does.
+type FailureNotifier interface {
+	Failures() <-chan error
+}
+
+var (
+	// ErrReloadUnsupported is the outcome of a reload, requested by SIGHUP or
+	// the control socket, of a Lifecycle implementing neither Reloader nor
+	// ContextReloader.
+	ErrReloadUnsupported = errors.New("lifecycle does not support reloading")
+	// ErrSignalUnsupported is the outcome of a signal Dissembler traps but
+	// has no handler for, such as SIGUSR1.
+	ErrSignalUnsupported = errors.New("signal not supported")
+)
+
 // Dissembler is
 type Dissembler struct {
+	// generation counts the configurations applied; it is first for 64-bit
+	// alignment.
+	generation uint64
+
 	lifecycle Lifecycle
+	// errc receives the error of a failed Start.
+	errc chan error
+	// quit is closed when Serve returns.
+	quit chan struct{}
+	// shutdown is cancelled by beginShutdown once the lifecycle begins
+	// stopping, or Serve returns, releasing the channels returned by Ticker
+	// and After and the goroutines started with Go.
+	shutdown       context.Context
+	cancelShutdown context.CancelFunc
+	// spawned are the goroutines started with Go, stopped in waves within
+	// stopConcurrency if set by WithStopConcurrency.
+	spawned         goroutineSet
+	stopConcurrency int64
+	// checks are the pre-flight checks run before Init.
+	checks []preflightCheck
+	// timezone and locale are pinned by WithTimezone and WithLocale.
+	timezone string
+	locale   string
+	// clock is the Clock set by WithClock.
+	clock Clock
+	// signals is the signal source set by WithSignals.
+	signals <-chan os.Signal
+	// notified and terminating relay the signals of the process to Wait,
+	// unless WithSignals is given. They are made once, and reused by every
+	// Wait.
+	notified    chan os.Signal
+	terminating chan os.Signal
+	// requests carries signals requested from within the process, such as by
+	// control commands, to Wait.
+	requests chan request
+	// controlPath is the control socket path set by WithControlSocket.
+	controlPath string
+	// runtimeDir is the runtime directory set by WithRuntimeDir.
+	runtimeDir string
+	// ownsRuntimeDir is set if the runtime directory is removed on exit,
+	// having been created by this process or handed over by its predecessor.
+	ownsRuntimeDir bool
+	// chroot and unshare are set by WithChroot and WithUnshare.
+	chroot  string
+	unshare Namespace
+	// dropCaps and keepCaps are set by WithKeepCapabilities.
+	dropCaps bool
+	keepCaps []Capability
+	// noNewPrivs and coreDumps are set by WithNoNewPrivs and WithCoreDumps.
+	noNewPrivs bool
+	coreDumps  *bool
+	// landlock are the rules set by WithLandlock.
+	landlock []LandlockRule
+	// seccomp is the profile set by WithSeccomp.
+	seccomp *SeccompProfile
+	// adminAddr and adminToken are the admin server address and token set by
+	// WithAdmin.
+	adminAddr  string
+	adminToken string
+	// adminTLS and approvedCrypto are set by WithAdminTLS and
+	// WithApprovedCrypto, and adminPolicy describes the TLS policy applied.
+	adminTLS       *tls.Config
+	approvedCrypto bool
+	adminPolicy    *TLSPolicy
+	// events are the recent events shown by the admin status page.
+	events eventLog
+	// sinks are the event sinks added with WithEventSink.
+	sinks []eventSink
+	// cloudMetadata is set by WithCloudMetadata.
+	cloudMetadata bool
+	// inhibit is set by WithShutdownInhibitor, and inhibitor is the lock
+	// taken.
+	inhibit   bool
+	inhibitor io.Closer
+	// quiesced is set while the lifecycle is quiesced over the admin API,
+	// serialized by quiesceMu.
+	quiesced  int32
+	quiesceMu sync.Mutex
+	// quiescedReload is set by WithQuiescedReload.
+	quiescedReload bool
+	// standby is set by WithStandby, deferring the start until activated
+	// by an activator or Activate, and active once the lifecycle has
+	// started.
+	standby      bool
+	activators   []Activator
+	activated    chan struct{}
+	activateOnce sync.Once
+	active       int32
+	// fence is set by WithFence, and fenced once its lease is lost.
+	fence  Fence
+	fenced int32
+	// exitReason is why Serve returned, and exitCodes are set by
+	// WithExitCodes.
+	exitReason ExitReason
+	exitCodes  map[ExitReason]int
+	// leakGrace is set by WithLeakCheck, and goroutines are those running
+	// before the lifecycle started.
+	leakGrace  time.Duration
+	goroutines map[string]bool
+	// incarnation is counted in the runtime directory.
+	incarnation uint64
+	// stopBudgets are set by WithStopBudget.
+	stopBudgets map[os.Signal]time.Duration
+	// drainUntil and drainLead are set by WithDrainUntil, and schedule is
+	// the stop scheduled.
+	drainUntil func() time.Time
+	drainLead  time.Duration
+	schedule   stopSchedule
+	// maxWork is set by WithMaxWork, and maxWorkReached once it is reached.
+	maxWork        int64
+	maxWorkReached int32
+	// reloadInterval is set by WithReloadInterval.
+	reloadInterval time.Duration
+	// bake and healthChecks are set by WithReloadBake.
+	bake         time.Duration
+	healthChecks []HealthCheck
+	// strict is set by WithStrict.
+	strict bool
+	// certs are reloaded on every reload, as set by WithCertReloader.
+	certs []*CertReloader
+	// stops counts the times the lifecycle is stopped while serving, and
+	// stopped is set once it has, for CheckInvariants.
+	stops   int32
+	stopped int32
+	// successor is the process started by the last successful upgrade.
+	successor int
+	// barrier orders stopping with co-located processes, as set by
+	// WithStopOrder.
+	barrier *stopBarrier
+	// started is when Serve began.
+	started time.Time
+	// chaos injects failures when enabled by WithChaos.
+	chaos *chaos
+	// cpus is the CPU affinity set by WithCPUAffinity.
+	cpus []int
+	// nice is the niceness set by WithNice, applied if renice is set.
+	nice   int
+	renice bool
+	// autoMaxProcs is set by WithAutoMaxProcs.
+	autoMaxProcs bool
+	// gcConfig is the garbage collector configuration set by WithGCConfig.
+	gcConfig func() (GCConfig, error)
+	// flags loads the feature flags, as set by WithFlags.
+	flags func() (map[string]bool, error)
+	// watchNet and onNetwork are set by WatchNetwork.
+	watchNet  bool
+	onNetwork func(ctx context.Context) error
+	// disks and diskInterval are set by WatchDisk, and diskHooks registered
+	// with OnDiskCritical.
+	disks        []DiskThreshold
+	diskInterval time.Duration
+	diskHooks    []hook
+	// suspendHooks and resumeHooks are registered with OnSuspend and
+	// OnResume.
+	suspendHooks []hook
+	resumeHooks  []hook
+	// hooks are the OnStop hooks, run in parallel if parallelHooks is set.
+	hooks         []hook
+	parallelHooks bool
+	// report is the report of the most recent shutdown, guarded by
+	// reportMu.
+	report   ShutdownReport
+	reportMu sync.Mutex
+	// sigStats are the statistics of signals received, guarded by sigMu.
+	sigStats map[string]*SignalStat
+	sigMu    sync.Mutex
+	// restarts is how many times the lifecycle was handed over to a new
+	// process, carried over through upgrades.
+	restarts uint64
+
+	// state is the current State, read atomically. Transitions are
+	// serialized by stateMu, which also guards booted and stateChanged.
+	state   int32
+	stateMu sync.Mutex
+	// booted is set once the lifecycle first becomes ready.
+	booted bool
+	// stateChanged is closed and replaced on every transition, waking
+	// AwaitState.
+	stateChanged chan struct{}
+}
+
+// request is a signal requested from within the process. The outcome of
+// handling it is sent on reply, and ctx is passed to the lifecycle.
+type request struct {
+	ctx   context.Context
+	sig   os.Signal
+	reply chan error
 }
 
+// Option configures a Dissembler.
+type Option func(*Dissembler)
+
 func init() {
 	DissemblerLogger = log.New(
 		log.NewJSONEncoder(
-			log.RFC3339Formatter("timestamp"),
+			rfc3339Formatter("timestamp"),
 			log.MessageKey("message"),
 			log.LevelString("level"),
 		),
@@ -79,26 +292,142 @@ func init() {
 			log.String("dissembler_version", Version),
 		),
 	)
+	adoptDissemblerLogger()
 }
 
-// Register makes a dissembler available.
-//func Register(lc Lifecycle) *Dissembler {
-//	return &Dissembler{Registered: lc}
-//}
+// WithSignals makes Wait receive signals from ch instead of from the process,
+// so tests can deliver synthetic signals without signalling themselves.
+func WithSignals(ch <-chan os.Signal) Option {
+	return func(d *Dissembler) {
+		d.signals = ch
+	}
+}
+
+// New returns a Dissembler for the lifecycle, configured by opts.
+func New(lc Lifecycle, opts ...Option) *Dissembler {
+	d := &Dissembler{
+		lifecycle:   lc,
+		errc:        make(chan error, 1),
+		quit:        make(chan struct{}),
+		requests:    make(chan request),
+		notified:    make(chan os.Signal, 4),
+		terminating: make(chan os.Signal, 1),
+		activated:   make(chan struct{}),
+		generation:  1,
+	}
+	d.shutdown, d.cancelShutdown = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
 
 // Serve accepts a Dissembler lifecycle and then calls Serve with the provided
 // lifecycle for the application, service, or API.
-func Serve(lc Lifecycle) error {
-	dissembler := &Dissembler{lifecycle: lc}
-	return dissembler.Serve()
+func Serve(lc Lifecycle, opts ...Option) error {
+	return New(lc, opts...).Serve()
 }
 
 // Serve begins the lifecycle of the Dissembler.
 func (d *Dissembler) Serve() error {
-	err := d.lifecycle.Init()
+	adoptDissemblerLogger()
+	d.started = clockOr(d.clock).Now()
+	atomic.StoreInt32(&d.stops, 0)
+	atomic.StoreInt32(&d.stopped, 0)
+	startRun()
+	d.exitReason = ExitSetupFailed
+	defer d.checkLeaks()
+	defer d.beginShutdown()
+	if d.adminAddr != "" {
+		sub := Subscribe(d.events.record)
+		defer sub.Close()
+	}
+	for _, s := range d.sinks {
+		sub := s.subscribe()
+		defer sub.drain(clockOr(d.clock), SinkFlushTimeout)
+	}
+	d.exportState()
+	d.exportSignals()
+	d.restoreCounters()
+	d.exportCounters()
+	d.setState(StateStarting)
+	defer d.setState(StateStopped)
+	d.chaos.announce()
+
+	if err := d.pinTimezone(); err != nil {
+		return err
+	}
+	if err := d.pinCPUs(); err != nil {
+		return err
+	}
+	d.adjustMaxProcs()
+	if err := d.tuneGC(); err != nil {
+		return err
+	}
+	if err := d.loadFlags(); err != nil {
+		return err
+	}
+	d.detectCloud()
+	if err := d.preflight(); err != nil {
+		return err
+	}
+	if err := d.checkStrict(); err != nil {
+		return err
+	}
+	if err := d.lockDown(); err != nil {
+		return err
+	}
+	removeRuntimeDir, err := d.setupRuntimeDir()
 	if err != nil {
 		return err
 	}
+	defer removeRuntimeDir()
+	d.countIncarnation()
+	defer close(d.quit)
+	d.inhibitShutdown()
+	defer d.releaseInhibitor()
+	if err := d.barrier.join(); err != nil {
+		return err
+	}
+	defer d.barrier.leave()
+	d.watchPower()
+	d.watchNetwork()
+	d.watchDisk()
+	if d.controlPath != "" {
+		ln, err := listenControl(d.controlPath)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		go d.serveControl(ln)
+	}
+	if d.adminAddr != "" {
+		srv, err := d.serveAdmin()
+		if err != nil {
+			return err
+		}
+		defer closeAdmin(srv)
+	}
+
+	endInit := BootStep("init")
+	begin := clockOr(d.clock).Now()
+	err = d.lifecycle.Init()
+	err = phaseError("", PhaseInit, 1, clockOr(d.clock).Now().Sub(begin), err)
+	endInit()
+	if err != nil {
+		d.exitReason = ExitInitFailed
+		return err
+	}
+	listeners.closeInherited()
+	if err := d.sandbox(); err != nil {
+		return err
+	}
+	if err := d.applyLandlock(); err != nil {
+		return err
+	}
+	if err := d.dropCapabilities(); err != nil {
+		return err
+	}
 
 	/*
 		err = d.lifecycle.Start()
@@ -107,19 +436,19 @@ func (d *Dissembler) Serve() error {
 		}*/
 
 	// Starting process
-	go func() error {
-		err = d.lifecycle.Start()
-		if err != nil {
-			return err
-		}
-		return nil
-	}()
+	d.snapshotGoroutines()
+	if d.standby {
+		d.spawn("standby", d.standBy)
+	} else {
+		d.start()
+	}
 
 	// Block and await signals
 	if _, err := d.Wait(); nil != err {
-		DissemblerLogger.Error("Unable to finish waiting for Dissembler to shutdown",
+		msgWaitFailed.emit(
 			log.String("error", err.Error()),
 		)
+		return err
 	}
 
 	return nil
@@ -127,57 +456,381 @@ func (d *Dissembler) Serve() error {
 
 // Wait blocks awaiting Unix signals. Signals are handled in a similar manner as
 // Nginx and Unicorn: <http://unicorn.bogomips.org/SIGNALS.html>.
+//
+// If the lifecycle's Start fails, Wait stops the lifecycle and returns the
+// error.
+//
+// Signals are received from the process unless a source was supplied with
+// WithSignals. Each signal is handled before the next is received, except
+// that termination signals take priority over any other pending signal or
+// request, and stop the lifecycle before they are logged.
 func (d *Dissembler) Wait() (syscall.Signal, error) {
-	ch := make(chan os.Signal, 2)
-	signal.Notify(
-		ch,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGQUIT,
-		syscall.SIGTERM,
-		syscall.SIGUSR1,
-		syscall.SIGUSR2,
-	)
+	defer flushLogs()
+
+	ch, term := d.signals, (<-chan os.Signal)(nil)
+	if ch == nil {
+		signal.Notify(
+			d.notified,
+			syscall.SIGHUP,
+			syscall.SIGUSR1,
+			syscall.SIGUSR2,
+		)
+		signal.Notify(
+			d.terminating,
+			syscall.SIGINT,
+			syscall.SIGQUIT,
+			syscall.SIGTERM,
+		)
+		defer d.stopSignals()
+		ch, term = d.notified, d.terminating
+	}
+
 	for {
-		sig := <-ch
-		DissemblerLogger.Info("signal caught",
-			log.String("signal", sig.String()))
+		// A pending termination signal preempts everything else.
+		select {
+		case sig := <-term:
+			d.recordSignal(sig, false)
+			publish(Event{Type: EventSignal, Time: clockOr(d.clock).Now(), Signal: sig})
+			return d.terminate(context.Background(), sig.(syscall.Signal), nil)
+		default:
+		}
+
+		var sig os.Signal
+		var reply chan error
+		ctx := context.Background()
+		select {
+		case sig = <-term:
+			d.recordSignal(sig, false)
+		case sig = <-ch:
+			d.recordSignal(sig, d.ignores(sig))
+		case req := <-d.requests:
+			sig, reply, ctx = req.sig, req.reply, req.ctx
+		case err := <-d.errc:
+			msgLifecycleFailedToStart.emit(
+				log.String("error", err.Error()))
+			d.exitReason = ExitFailed
+			d.stop(ctx)
+			return 0, err
+		}
+		publish(Event{Type: EventSignal, Time: clockOr(d.clock).Now(), Signal: sig})
+
+		switch sig {
+
+		// SIGINT should exit, SIGQUIT should exit gracefully and SIGTERM should
+		// exit.
+		case syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM:
+			return d.terminate(ctx, sig.(syscall.Signal), reply)
+		}
+
+		logAsync(func() {
+			msgSignalCaught.emit(
+				log.String("signal", sig.String()))
+		})
 		switch sig {
 
-		/*
-					// SIGHUP reloads configuration.
-			    case syscall.SIGHUP:
-			    d.Registered.Reload()
-			    return syscall.SIGHUP, nil
-		*/
-
-		// SIGINT should exit.
-		case syscall.SIGINT:
-			d.lifecycle.Stop()
-			return syscall.SIGINT, nil
-
-		// SIGQUIT should exit gracefully.
-		case syscall.SIGQUIT:
-			d.lifecycle.Stop()
-			return syscall.SIGQUIT, nil
-
-		// SIGTERM should exit.
-		case syscall.SIGTERM:
-			d.lifecycle.Stop()
-			return syscall.SIGTERM, nil
-
-			/*
-				// SIGUSR2 forks and re-execs the first time it is received and execs
-				// without forking from then on.
-				case syscall.SIGUSR2:
-					if forked {
-						return syscall.SIGUSR2, nil
-					}
-					forked = true
-					if err := ForkExec(l); nil != err {
-						return syscall.SIGUSR2, err
-					}
-			*/
+		// SIGHUP reloads configuration.
+		case syscall.SIGHUP:
+			err := d.reload(ctx)
+			respond(reply, err)
+			if d.strict && errors.Is(err, ErrReloadUnsupported) {
+				return d.failStrict(ctx, fmt.Sprintf("%s: %v", sig, err))
+			}
+
+		// SIGUSR2 re-executes the binary, handing over listeners opened through
+		// Listen and ListenPacket, and exits gracefully once the new process is
+		// ready. If the upgrade fails, the process keeps serving.
+		case syscall.SIGUSR2:
+			end := d.transition(StateUpgrading)
+			err := d.upgrade()
+			end()
+			if err != nil {
+				msgUnableToUpgrade.emit(
+					log.String("error", err.Error()),
+				)
+				respond(reply, err)
+				continue
+			}
+			respond(reply, nil)
+			d.exitReason = ExitUpgraded
+			d.stop(ctx)
+			return syscall.SIGUSR2, nil
+
+		default:
+			msgSignalIgnored.emit(
+				log.String("signal", sig.String()),
+				log.String("error", ErrSignalUnsupported.Error()),
+			)
+			err := fmt.Errorf("%s: %w", sig, ErrSignalUnsupported)
+			respond(reply, err)
+			if d.strict {
+				return d.failStrict(ctx, err.Error())
+			}
 		}
 	}
 }
+
+// stopSignals stops relaying signals to Wait, discarding any still pending so
+// the next Wait starts afresh.
+func (d *Dissembler) stopSignals() {
+	signal.Stop(d.notified)
+	signal.Stop(d.terminating)
+	for _, ch := range []chan os.Signal{d.notified, d.terminating} {
+		for len(ch) > 0 {
+			<-ch
+		}
+	}
+}
+
+// ignores reports whether sig has no effect on the lifecycle.
+func (d *Dissembler) ignores(sig os.Signal) bool {
+	switch sig {
+	case syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGUSR2:
+		return false
+	case syscall.SIGHUP:
+		return !canReload(d.lifecycle)
+	}
+	return true
+}
+
+// canReload reports whether lc implements Reloader or ContextReloader.
+func canReload(lc Lifecycle) bool {
+	switch lc.(type) {
+	case Reloader, ContextReloader:
+		return true
+	}
+	return false
+}
+
+// terminate stops the lifecycle in response to the termination signal sig,
+// logging the signal off the shutdown path.
+func (d *Dissembler) terminate(ctx context.Context, sig syscall.Signal, reply chan error) (syscall.Signal, error) {
+	logAsync(func() {
+		msgSignalCaught.emit(
+			log.String("signal", sig.String()))
+	})
+	respond(reply, nil)
+	d.exitReason = ExitStopped
+	if atomic.LoadInt32(&d.maxWorkReached) == 1 {
+		d.exitReason = ExitMaxWork
+	}
+	if atomic.LoadInt32(&d.fenced) == 1 {
+		d.exitReason = ExitLeaseLost
+	}
+	ctx, cancel := d.stopContext(ctx, sig)
+	defer cancel()
+	d.stop(ctx)
+	return sig, nil
+}
+
+// request asks Wait to handle sig as if it had been received, returning the
+// outcome once it has been handled, or errNotServing if the lifecycle is
+// stopping or Serve has returned.
+func (d *Dissembler) request(ctx context.Context, sig os.Signal) error {
+	req := request{ctx: ctx, sig: sig, reply: make(chan error, 1)}
+	select {
+	case d.requests <- req:
+	case <-d.shutdown.Done():
+		return errNotServing
+	case <-d.quit:
+		return errNotServing
+	}
+	select {
+	case err := <-req.reply:
+		return err
+	case <-d.quit:
+		return errNotServing
+	}
+}
+
+// respond sends the outcome of handling a requested signal, if it was
+// requested.
+func respond(reply chan<- error, err error) {
+	if reply != nil {
+		reply <- err
+	}
+}
+
+// awaitReady completes the boot trace once the lifecycle is ready, recording
+// the wait as a boot step. Lifecycles not implementing ReadyChecker are ready
+// once started.
+func (d *Dissembler) awaitReady() {
+	rc, ok := d.lifecycle.(ReadyChecker)
+	if !ok {
+		d.ready()
+		return
+	}
+
+	end := BootStep("ready")
+	for rc.Ready() != nil {
+		select {
+		case <-clockOr(d.clock).After(bootReadyPoll):
+		case <-d.quit:
+			end()
+			return
+		}
+	}
+	end()
+	d.ready()
+}
+
+// start starts the lifecycle, then awaits its readiness and watches the work
+// it does.
+func (d *Dissembler) start() {
+	atomic.StoreInt32(&d.active, 1)
+	d.spawn("start", func(context.Context) error {
+		clock := clockOr(d.clock)
+		d.chaos.delayStart(clock)
+		begin := clock.Now()
+		err := d.lifecycle.Start()
+		return phaseError("", PhaseStart, 1, clock.Now().Sub(begin), err)
+	})
+	if f, ok := d.lifecycle.(FailureNotifier); ok {
+		failures := f.Failures()
+		d.spawn("failures", func(ctx context.Context) error {
+			select {
+			case err := <-failures:
+				return err
+			case <-ctx.Done():
+				return nil
+			}
+		})
+	}
+	go d.awaitReady()
+	d.watchWork()
+	d.scheduleReloads()
+}
+
+// ready installs any seccomp filter, completes the boot and, if this process
+// was started by an upgrade, tells the parent it may stop.
+func (d *Dissembler) ready() {
+	d.applySeccomp()
+	d.markReady()
+	boot.finish()
+	d.notifyParent()
+	d.loadDrainUntil()
+}
+
+// stop stops the lifecycle, once any processes ordered to stop first have,
+// reporting drain progress while it stops if the lifecycle implements
+// DrainReporter, then runs the OnStop hooks and records the shutdown report.
+func (d *Dissembler) stop(ctx context.Context) {
+	d.setState(StateStopping)
+	defer d.setState(StateStopped)
+	d.beginShutdown()
+	if n := atomic.AddInt32(&d.stops, 1); n > 1 {
+		violate("lifecycle stopped %d times", n)
+	}
+
+	clock := clockOr(d.clock)
+	report := ShutdownReport{Started: clock.Now()}
+	d.barrier.await(clock)
+
+	var drained chan struct{}
+	if r, ok := d.lifecycle.(DrainReporter); ok || d.spawned.len() > 0 {
+		drained = make(chan struct{})
+		go reportDrain(clock, drainProgress{d: d, r: r}, drained)
+	}
+
+	d.chaos.hangStop(clock)
+
+	begin := clock.Now()
+	err := d.stopLifecycle(ctx)
+	err = phaseError("", PhaseStop, 1, clock.Now().Sub(begin), err)
+	if err != nil {
+		msgUnableToStopLifecycle.emit(
+			log.String("error", err.Error()),
+		)
+		report.StopErr = err
+		if d.exitReason == ExitStopped {
+			d.exitReason = ExitStopFailed
+		}
+	}
+	report.Goroutines = d.awaitGoroutines(ctx)
+	report.StopDuration = clock.Now().Sub(report.Started)
+	atomic.StoreInt32(&d.stopped, 1)
+	if drained != nil {
+		close(drained)
+	}
+
+	report.Hooks = d.runHooks()
+	report.Duration = clock.Now().Sub(report.Started)
+	if err := d.checkStrictStop(report); err != nil && d.exitReason == ExitStopped {
+		d.exitReason = ExitStopFailed
+	}
+	d.releaseInhibitor()
+	d.barrier.leave()
+
+	d.reportMu.Lock()
+	d.report = report
+	d.reportMu.Unlock()
+	logReport(report)
+}
+
+// Generation returns the configuration generation: 1 for the configuration
+// loaded at boot, incremented by every successful reload.
+func (d *Dissembler) Generation() uint64 {
+	return atomic.LoadUint64(&d.generation)
+}
+
+// reload re-evaluates GOMAXPROCS, the GC configuration and the feature flags,
+// reloads the certificates registered with WithCertReloader and reloads the
+// lifecycle if it implements ContextReloader or Reloader, returning
+// ErrReloadUnsupported if it implements neither. Reload failures are logged and
+// returned, and the current configuration is kept. With WithReloadBake, the new
+// configuration is rolled back should it fail health checks while baking.
+func (d *Dissembler) reload(ctx context.Context) error {
+	defer d.transition(StateReloading)()
+	d.adjustMaxProcs()
+	if err := d.tuneGC(); err != nil {
+		msgUnableToReloadGC.emit(
+			log.String("error", err.Error()),
+		)
+		return err
+	}
+	if err := d.loadFlags(); err != nil {
+		msgUnableToReloadFlags.emit(
+			log.String("error", err.Error()),
+		)
+		return err
+	}
+	if err := d.reloadCerts(); err != nil {
+		return err
+	}
+
+	if !canReload(d.lifecycle) {
+		msgReloadUnsupported.emit(
+			log.String("error", ErrReloadUnsupported.Error()),
+		)
+		return ErrReloadUnsupported
+	}
+	resume, err := d.quiesceForReload(ctx)
+	if err != nil {
+		return err
+	}
+	defer resume()
+
+	err = d.chaos.failReload()
+	if err == nil {
+		begin := clockOr(d.clock).Now()
+		if r, ok := d.lifecycle.(ContextReloader); ok {
+			err = r.ReloadContext(ctx)
+		} else {
+			err = d.lifecycle.(Reloader).Reload()
+		}
+		err = phaseError("", PhaseReload, 1, clockOr(d.clock).Now().Sub(begin), err)
+	}
+	if err != nil {
+		msgReloadFailed.emit(
+			log.String("error", err.Error()),
+		)
+		return err
+	}
+	if err := d.bakeReload(ctx); err != nil {
+		return err
+	}
+	msgReloaded.emit(
+		log.Int64("generation", int64(atomic.AddUint64(&d.generation, 1))),
+	)
+	d.loadDrainUntil()
+	return nil
+}
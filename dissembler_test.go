@@ -0,0 +1,105 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// stopTimer is a Lifecycle recording when Stop is called.
+type stopTimer struct {
+	stopped chan time.Time
+}
+
+func (l *stopTimer) Init() error  { return nil }
+func (l *stopTimer) Start() error { return nil }
+func (l *stopTimer) Stop() error {
+	l.stopped <- time.Now()
+	return nil
+}
+
+// reloader is a Lifecycle whose Reload does nothing.
+type reloader struct {
+	stopTimer
+}
+
+func (*reloader) Reload() error { return nil }
+
+// discardLogs silences the logger until the benchmark ends.
+func discardLogs(b *testing.B) {
+	previous := Logger()
+	SetLogger(log.New(log.NewJSONEncoder(), log.DiscardOutput))
+	b.Cleanup(func() { SetLogger(previous) })
+}
+
+// BenchmarkSignalToStop measures the latency from a SIGTERM being sent to the
+// process to the lifecycle's Stop being called, reported as ns/stop.
+func BenchmarkSignalToStop(b *testing.B) {
+	discardLogs(b)
+	defer signal.Reset(syscall.SIGTERM)
+
+	var latency time.Duration
+	for i := 0; i < b.N; i++ {
+		lc := &stopTimer{stopped: make(chan time.Time, 1)}
+		d := New(lc)
+
+		// SIGTERM is ignored until Wait subscribes to it, so a signal can
+		// never kill the benchmark, and Notify lifting the ignore shows that
+		// Wait is subscribing. Stopping an unknown channel then waits for
+		// Notify to return, once the handler is installed.
+		signal.Ignore(syscall.SIGTERM)
+		done := make(chan struct{})
+		go func() {
+			d.Wait()
+			close(done)
+		}()
+		for signal.Ignored(syscall.SIGTERM) {
+			runtime.Gosched()
+		}
+		signal.Stop(make(chan os.Signal))
+
+		sent := time.Now()
+		if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+			b.Fatal(err)
+		}
+		latency += (<-lc.stopped).Sub(sent)
+		<-done
+	}
+	b.ReportMetric(float64(latency.Nanoseconds())/float64(b.N), "ns/stop")
+}
+
+// BenchmarkWait measures Wait handling a reload request, the per-signal cost
+// of the Wait loop.
+func BenchmarkWait(b *testing.B) {
+	discardLogs(b)
+	d := New(&reloader{stopTimer{stopped: make(chan time.Time, 1)}},
+		WithSignals(make(chan os.Signal)))
+	d.setState(StateStarting)
+	d.markReady()
+	done := make(chan struct{})
+	go func() {
+		d.Wait()
+		close(done)
+	}()
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.request(ctx, syscall.SIGHUP); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	d.request(ctx, syscall.SIGTERM)
+	<-done
+}
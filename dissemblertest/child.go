@@ -0,0 +1,341 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissemblertest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dissembler/dissembler"
+)
+
+// envChild names the lifecycle a child process started by StartChild runs.
+const envChild = "DISSEMBLERTEST_CHILD"
+
+// childQueue is the event queue of a child's event stream, large enough not
+// to drop events during signal storms.
+const childQueue = 1 << 16
+
+// ChildMain runs the lifecycle named by StartChild, built by the matching
+// entry of children and configured by opts, if the process is a child started
+// by StartChild, and exits once it stops; otherwise it returns immediately.
+// Call it first in TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		dissemblertest.ChildMain(map[string]func() dissembler.Lifecycle{
+//			"server": newServer,
+//		})
+//		os.Exit(m.Run())
+//	}
+//
+// The child logs to standard output, and writes every state and signal event
+// there as a JSON line too, for the parent to observe.
+func ChildMain(children map[string]func() dissembler.Lifecycle, opts ...dissembler.Option) {
+	name := os.Getenv(envChild)
+	if name == "" {
+		return
+	}
+	newLifecycle, ok := children[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dissemblertest: no child lifecycle %q\n", name)
+		os.Exit(2)
+	}
+	dissembler.Run(newLifecycle(), append(opts,
+		dissembler.WithEventSink(&lineSink{w: os.Stdout}, dissembler.EventQueue(childQueue)),
+	)...)
+}
+
+// lineSink writes events as JSON lines.
+type lineSink struct {
+	w io.Writer
+}
+
+// Send writes the state and signal events of a batch.
+func (s *lineSink) Send(events []dissembler.Event) error {
+	enc := json.NewEncoder(s.w)
+	for _, e := range events {
+		if e.Type != dissembler.EventState && e.Type != dissembler.EventSignal {
+			continue
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record is a line written by a child process: a log entry, an event, or
+// other output.
+type Record struct {
+	// RunID is the ID of the run that wrote the record.
+	RunID string `json:"run_id"`
+	// PID is the process of an event.
+	PID int `json:"pid"`
+	// Message and MessageID are set for log entries.
+	Message   string `json:"message"`
+	MessageID string `json:"message_id"`
+	// Type is the type of an event, and State or Signal what it reports.
+	Type   string `json:"type"`
+	State  string `json:"state"`
+	Signal string `json:"signal"`
+	// Line is the line as written.
+	Line string `json:"-"`
+}
+
+// Child is a real process running a lifecycle, started by StartChild, and the
+// processes replacing it through upgrades.
+type Child struct {
+	// Cmd is the first process.
+	Cmd *exec.Cmd
+
+	mu      sync.Mutex
+	changed chan struct{}
+	records []Record
+	eof     bool
+}
+
+// StartChild starts the test binary again as a child process running the
+// lifecycle registered as name with ChildMain, and waits for it to become
+// ready, within ReadyTimeout. Cleanup registered with t kills every process of
+// the child still running.
+//
+// Signals sent through the Child go to the process currently serving, so
+// tests can bombard it with signal storms and sequences such as SIGTERM
+// during a reload or SIGUSR2 during a drain, then check from the outside what
+// the processes reported, with CheckTransitions among others.
+func StartChild(t testing.TB, name string, args ...string) *Child {
+	t.Helper()
+
+	// Standard output is a pipe of our own rather than Cmd.StdoutPipe, which
+	// is closed once the first process exits, while the processes replacing
+	// it through upgrades still write to it.
+	out, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("dissemblertest: %v", err)
+	}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), envChild+"="+name)
+	cmd.Stdout, cmd.Stderr = w, os.Stderr
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		out.Close()
+		t.Fatalf("dissemblertest: starting child: %v", err)
+	}
+
+	c := &Child{Cmd: cmd, changed: make(chan struct{})}
+	go c.read(out)
+	go cmd.Wait()
+	t.Cleanup(c.kill)
+
+	if !c.AwaitState(dissembler.StateRunning, ReadyTimeout) {
+		t.Fatalf("dissemblertest: child %s not ready within %s", name, ReadyTimeout)
+	}
+	return c
+}
+
+// read records the lines written by the child's processes until all of them
+// have closed standard output.
+func (c *Child) read(r io.ReadCloser) {
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		rec := Record{Line: s.Text()}
+		json.Unmarshal(s.Bytes(), &rec)
+		c.update(func() { c.records = append(c.records, rec) })
+	}
+	c.update(func() { c.eof = true })
+}
+
+// update changes the recorded state under the lock, waking waiters.
+func (c *Child) update(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn()
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Records returns the lines written so far by the child's processes.
+func (c *Child) Records() []Record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Record(nil), c.records...)
+}
+
+// Await waits up to timeout for a record matching match, returning it.
+func (c *Child) Await(match func(Record) bool, timeout time.Duration) (Record, bool) {
+	var found Record
+	ok := c.await(func() bool {
+		for _, rec := range c.records {
+			if match(rec) {
+				found = rec
+				return true
+			}
+		}
+		return false
+	}, timeout)
+	return found, ok
+}
+
+// AwaitState waits up to timeout for the process currently serving to report
+// entering state, reporting whether it did.
+func (c *Child) AwaitState(state dissembler.State, timeout time.Duration) bool {
+	return c.await(func() bool {
+		run := c.current()
+		return run != nil && run.states[len(run.states)-1] == state.String()
+	}, timeout)
+}
+
+// Wait waits up to timeout for every process of the child to exit, reporting
+// whether they did.
+func (c *Child) Wait(timeout time.Duration) bool {
+	return c.await(func() bool { return c.eof }, timeout)
+}
+
+// await waits up to timeout for cond, evaluated under the lock whenever
+// records change, to hold.
+func (c *Child) await(cond func() bool, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		ok, changed := cond(), c.changed
+		c.mu.Unlock()
+		if ok {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// Signal sends sig to the process currently serving.
+func (c *Child) Signal(sig os.Signal) error {
+	c.mu.Lock()
+	run := c.current()
+	c.mu.Unlock()
+	if run == nil {
+		return fmt.Errorf("dissemblertest: no child process serving")
+	}
+	p, err := os.FindProcess(run.pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
+
+// Storm sends sig n times, interval apart, to the process serving at each
+// send, stopping at the first failure.
+func (c *Child) Storm(sig os.Signal, n int, interval time.Duration) error {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		if err := c.Signal(sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckTransitions fails the test unless every process of the child began
+// starting and went through states as dissembler.LegalTransition allows.
+func (c *Child) CheckTransitions(t testing.TB) {
+	t.Helper()
+	c.mu.Lock()
+	runs := c.runs()
+	c.mu.Unlock()
+
+	for _, run := range runs {
+		if run.states[0] != dissembler.StateStarting.String() {
+			t.Errorf("dissemblertest: run %s (pid %d) began %s, want %s",
+				run.id, run.pid, run.states[0], dissembler.StateStarting)
+		}
+		for i := 1; i < len(run.states); i++ {
+			from, to := run.states[i-1], run.states[i]
+			if !dissembler.LegalTransition(stateNamed(from), stateNamed(to)) {
+				t.Errorf("dissemblertest: run %s (pid %d) moved from %s to %s",
+					run.id, run.pid, from, to)
+			}
+		}
+	}
+}
+
+// stateNamed returns the state named name.
+func stateNamed(name string) dissembler.State {
+	for s := dissembler.StateIdle; s <= dissembler.StateStopped; s++ {
+		if s.String() == name {
+			return s
+		}
+	}
+	return -1
+}
+
+// run is the states a process of the child reported during one run.
+type run struct {
+	id     string
+	pid    int
+	states []string
+}
+
+// runs groups the state events recorded by run, in the order runs began. mu
+// must be held.
+func (c *Child) runs() []*run {
+	var runs []*run
+	byID := make(map[string]*run)
+	for _, rec := range c.records {
+		if rec.Type != "state" {
+			continue
+		}
+		r, ok := byID[rec.RunID]
+		if !ok {
+			r = &run{id: rec.RunID, pid: rec.PID}
+			byID[rec.RunID] = r
+			runs = append(runs, r)
+		}
+		r.states = append(r.states, rec.State)
+	}
+	return runs
+}
+
+// current returns the most recently begun run that has not stopped, or nil.
+// mu must be held.
+func (c *Child) current() *run {
+	runs := c.runs()
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].states[len(runs[i].states)-1] != dissembler.StateStopped.String() {
+			return runs[i]
+		}
+	}
+	return nil
+}
+
+// kill kills every process of the child still running.
+func (c *Child) kill() {
+	c.mu.Lock()
+	runs := c.runs()
+	c.mu.Unlock()
+
+	c.Cmd.Process.Kill()
+	for _, run := range runs {
+		if run.states[len(run.states)-1] == dissembler.StateStopped.String() {
+			continue
+		}
+		if p, err := os.FindProcess(run.pid); err == nil {
+			p.Kill()
+		}
+	}
+	c.Wait(ShutdownTimeout)
+}
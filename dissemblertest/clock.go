@@ -0,0 +1,160 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissemblertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dissembler/dissembler"
+)
+
+var _ dissembler.Clock = (*FakeClock)(nil)
+
+// FakeClock is a dissembler.Clock whose time only moves when Advance is
+// called, so tests of timeouts, backoffs and tickers run deterministically and
+// without sleeping.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{}
+}
+
+// waiter is a pending timer or ticker.
+type waiter struct {
+	clock    *FakeClock
+	deadline time.Time
+	period   time.Duration
+	c        chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer that fires once the clock has been advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) dissembler.Timer {
+	return c.add(d, 0)
+}
+
+// NewTicker returns a Ticker that fires each time the clock advances past
+// another period.
+func (c *FakeClock) NewTicker(period time.Duration) dissembler.Ticker {
+	if period <= 0 {
+		panic("dissemblertest: non-positive interval for NewTicker")
+	}
+	return &fakeTicker{c.add(period, period)}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker due in
+// that time in deadline order. Like time.Ticker, a ticker that has not been
+// drained drops ticks.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		sort.Slice(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(end) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.deadline
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = end
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, so a test
+// can advance the clock only once the code under test is waiting on it.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.waiters), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add registers a waiter firing after d and then every period, if positive.
+func (c *FakeClock) add(d, period time.Duration) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{clock: c, deadline: c.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- c.now
+		if period == 0 {
+			return w
+		}
+		w.deadline = c.now.Add(period)
+	}
+	c.waiters = append(c.waiters, w)
+	c.notify()
+	return w
+}
+
+// remove cancels a waiter, reporting whether it was pending.
+func (c *FakeClock) remove(w *waiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// notify wakes BlockUntil callers. The caller must hold c.mu.
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// C returns the channel on which the timer fires.
+func (w *waiter) C() <-chan time.Time { return w.c }
+
+// Stop cancels the timer, reporting whether it was pending.
+func (w *waiter) Stop() bool { return w.clock.remove(w) }
+
+// fakeTicker adapts a periodic waiter to dissembler.Ticker.
+type fakeTicker struct{ w *waiter }
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t *fakeTicker) Stop()               { t.w.clock.remove(t.w) }
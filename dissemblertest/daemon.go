@@ -0,0 +1,127 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissemblertest
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dissembler/dissembler"
+)
+
+const (
+	// ReadyTimeout bounds how long StartDaemon waits for the lifecycle to
+	// become ready.
+	ReadyTimeout = 10 * time.Second
+	// ShutdownTimeout bounds how long a daemon's cleanup waits for it to
+	// stop. Goroutines it started are then given
+	// dissembler.DefaultLeakGrace to exit.
+	ShutdownTimeout = 10 * time.Second
+)
+
+// Daemon is a lifecycle served in-process by StartDaemon.
+type Daemon struct {
+	// Dissembler serves the lifecycle.
+	*dissembler.Dissembler
+	// Signals delivers signals to the Dissembler in place of the process.
+	Signals *Signals
+
+	done chan struct{}
+	err  error
+}
+
+// StartDaemon serves lc in-process, configured by opts and receiving signals
+// from the returned Daemon's Signals, and waits for it to become ready. The
+// test fails if lc is not ready within ReadyTimeout.
+//
+// Cleanup registered with t stops the daemon gracefully with SIGTERM, failing
+// the test if it does not stop within ShutdownTimeout, if Serve returns an
+// error, if dissembler.CheckInvariants reports a violation, or if goroutines
+// started since the lifecycle started are still running, as checked by
+// dissembler.WithLeakCheck. Tests starting daemons should not run in
+// parallel, as goroutines of other tests would be reported as leaked.
+func StartDaemon(t testing.TB, lc dissembler.Lifecycle, opts ...dissembler.Option) *Daemon {
+	t.Helper()
+
+	signals := NewSignals()
+	d := &Daemon{
+		Dissembler: dissembler.New(lc, append(opts,
+			signals.Option(),
+			dissembler.WithLeakCheck(dissembler.DefaultLeakGrace),
+		)...),
+		Signals: signals,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(d.done)
+		d.err = d.Serve()
+	}()
+	t.Cleanup(func() { d.shutdown(t) })
+
+	deadline := time.Now().Add(ReadyTimeout)
+	for d.State() != dissembler.StateRunning {
+		select {
+		case <-d.done:
+			t.Fatalf("dissemblertest: daemon stopped before becoming ready: %v", d.err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dissemblertest: daemon not ready within %s", ReadyTimeout)
+		}
+	}
+	return d
+}
+
+// shutdown stops the daemon and checks that it stopped cleanly, leaving none
+// of the goroutines it started running.
+func (d *Daemon) shutdown(t testing.TB) {
+	t.Helper()
+
+	timeout := time.After(ShutdownTimeout + dissembler.DefaultLeakGrace)
+	select {
+	case d.Signals.ch <- syscall.SIGTERM:
+	case <-d.done:
+	case <-timeout:
+	}
+	select {
+	case <-d.done:
+	case <-timeout:
+		t.Errorf("dissemblertest: daemon did not stop within %s", ShutdownTimeout)
+		return
+	}
+	if d.err != nil {
+		t.Errorf("dissemblertest: daemon failed: %v", d.err)
+	}
+	if err := dissembler.CheckInvariants(); err != nil {
+		t.Errorf("dissemblertest: %v", err)
+	}
+
+	var leaked []string
+	for _, stack := range d.ShutdownReport().Leaked {
+		if !ignored(stack) {
+			leaked = append(leaked, stack)
+		}
+	}
+	if len(leaked) > 0 {
+		t.Errorf("dissemblertest: %d goroutines leaked by daemon:\n\n%s",
+			len(leaked), strings.Join(leaked, "\n\n"))
+	}
+}
+
+// ignored reports whether the goroutine running stack belongs to the testing
+// package, such as one running another test.
+func ignored(stack string) bool {
+	for _, s := range []string{
+		"testing.(*T).Run",
+		"testing.tRunner",
+		"testing.runTests",
+	} {
+		if strings.Contains(stack, s) {
+			return true
+		}
+	}
+	return false
+}
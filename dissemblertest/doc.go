@@ -0,0 +1,8 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+// Package dissemblertest provides utilities for testing lifecycles run by
+// Dissembler, such as a fake clock for advancing time deterministically, a
+// synthetic signal source, an in-process daemon fixture, and a harness running
+// a lifecycle in a real child process to bombard with signals.
+package dissemblertest
@@ -0,0 +1,34 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissemblertest
+
+import (
+	"os"
+
+	"github.com/dissembler/dissembler"
+)
+
+// Signals is a synthetic signal source for a Dissembler, replacing delivery of
+// real signals to the test process.
+type Signals struct {
+	ch chan os.Signal
+}
+
+// NewSignals returns a synthetic signal source.
+func NewSignals() *Signals {
+	return &Signals{ch: make(chan os.Signal)}
+}
+
+// Option configures a Dissembler to receive signals from s.
+func (s *Signals) Option() dissembler.Option {
+	return dissembler.WithSignals(s.ch)
+}
+
+// Send delivers sig, blocking until the Dissembler receives it. Because each
+// signal is handled before the next is received, once a second Send returns
+// the first signal has been fully handled, so tests can assert handling order
+// deterministically.
+func (s *Signals) Send(sig os.Signal) {
+	s.ch <- sig
+}
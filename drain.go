@@ -0,0 +1,68 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// drainReportInterval is how often drain progress is reported while stopping.
+const drainReportInterval = time.Second
+
+// drainRemaining is the number of in-flight units remaining while stopping.
+var drainRemaining = new(expvar.Int)
+
+func init() {
+	Metrics.Set(metricDrainRemaining, drainRemaining)
+}
+
+// DrainReporter is an optional interface that may be implemented by a
+// Lifecycle to report how many in-flight units of work, such as connections or
+// jobs, remain while it is stopping. While Stop runs, Dissembler periodically
+// logs and exports the remaining count so long drains are observable.
+type DrainReporter interface {
+	DrainProgress() int
+}
+
+// DrainProgress returns the sum of the in-flight units remaining in every
+// component implementing DrainReporter.
+func (g *Group) DrainProgress() int {
+	g.mu.Lock()
+	components := append([]*component(nil), g.components...)
+	g.mu.Unlock()
+
+	var remaining int
+	for _, c := range components {
+		if r, ok := c.lc.(DrainReporter); ok {
+			remaining += r.DrainProgress()
+		}
+	}
+	return remaining
+}
+
+// reportDrain logs and exports the lifecycle's drain progress every
+// drainReportInterval until done is closed.
+func reportDrain(clock Clock, r DrainReporter, done <-chan struct{}) {
+	start := clock.Now()
+	ticker := clock.NewTicker(drainReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			remaining := r.DrainProgress()
+			drainRemaining.Set(int64(remaining))
+			msgDraining.emit(
+				log.Int("remaining", remaining),
+				log.Duration("elapsed", clock.Now().Sub(start)),
+			)
+		case <-done:
+			drainRemaining.Set(0)
+			return
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultEventQueue is the number of events queued for a subscriber when
+	// EventQueue is not given.
+	DefaultEventQueue = 256
+	// DefaultEventBatch is the most events delivered to a subscriber at once
+	// when EventBatch is not given.
+	DefaultEventBatch = 64
+)
+
+// EventType identifies what an Event reports.
+type EventType int
+
+const (
+	// EventState reports that the Dissembler entered State.
+	EventState EventType = iota
+	// EventComponent reports that the Group component Component entered
+	// ComponentState, failing with Err if it is degraded.
+	EventComponent
+	// EventSignal reports that Signal was received.
+	EventSignal
+	// EventDisk reports that free space on the filesystem holding Path,
+	// DiskFree bytes, entered DiskLevel.
+	EventDisk
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventState:
+		return "state"
+	case EventComponent:
+		return "component"
+	case EventSignal:
+		return "signal"
+	case EventDisk:
+		return "disk"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is a lifecycle event. The fields set depend on Type.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	State          State
+	Component      string
+	ComponentState ComponentState
+	Signal         os.Signal
+	Path           string
+	DiskLevel      DiskLevel
+	DiskFree       int64
+	Err            error
+	// RunID is the ID of the run the event occurred in, set when it is
+	// published.
+	RunID string
+}
+
+// eventsDropped is the number of events dropped across all subscribers.
+var eventsDropped = new(expvar.Int)
+
+func init() {
+	Metrics.Set(metricEventsDropped, eventsDropped)
+}
+
+// subscribers are the current event subscriptions.
+var subscribers struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription is a subscriber to lifecycle events. Events are queued for the
+// subscriber without blocking the publisher and delivered in batches on the
+// subscription's own goroutine; when the queue is full, further events are
+// dropped and counted until the subscriber catches up, so a slow subscriber,
+// such as a webhook notifier, never stalls lifecycle processing.
+type Subscription struct {
+	// delivered and dropped are first for 64-bit alignment.
+	delivered uint64
+	dropped   uint64
+
+	fn    func([]Event)
+	batch int
+	queue chan Event
+	done  chan struct{}
+	once  sync.Once
+
+	// draining is closed to have the queued events delivered before the
+	// subscription ends; exited is closed once delivery has stopped.
+	draining  chan struct{}
+	drainOnce sync.Once
+	exited    chan struct{}
+}
+
+// SubscribeOption configures a Subscription.
+type SubscribeOption func(*Subscription)
+
+// EventQueue sets how many events are queued for the subscriber before
+// further events are dropped.
+func EventQueue(n int) SubscribeOption {
+	return func(s *Subscription) {
+		s.queue = make(chan Event, n)
+	}
+}
+
+// EventBatch sets the most events delivered to the subscriber at once.
+func EventBatch(n int) SubscribeOption {
+	return func(s *Subscription) {
+		s.batch = n
+	}
+}
+
+// Subscribe calls fn with batches of lifecycle events, in the order they
+// occurred, until the returned Subscription is closed. The batch is reused
+// once fn returns, so fn must copy any events it retains.
+func Subscribe(fn func([]Event), opts ...SubscribeOption) *Subscription {
+	s := &Subscription{
+		fn:       fn,
+		batch:    DefaultEventBatch,
+		queue:    make(chan Event, DefaultEventQueue),
+		done:     make(chan struct{}),
+		draining: make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.batch < 1 {
+		s.batch = 1
+	}
+
+	subscribers.mu.Lock()
+	if subscribers.subs == nil {
+		subscribers.subs = make(map[*Subscription]struct{})
+	}
+	subscribers.subs[s] = struct{}{}
+	subscribers.mu.Unlock()
+
+	go s.deliver()
+	return s
+}
+
+// Delivered returns the number of events delivered to the subscriber.
+func (s *Subscription) Delivered() uint64 {
+	return atomic.LoadUint64(&s.delivered)
+}
+
+// Dropped returns the number of events dropped because the subscriber's queue
+// was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close ends the subscription. Events already queued are discarded, though a
+// batch being delivered when Close is called completes.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		subscribers.mu.Lock()
+		delete(subscribers.subs, s)
+		subscribers.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// drain ends the subscription once the events already queued have been
+// delivered, waiting up to timeout for them.
+func (s *Subscription) drain(clock Clock, timeout time.Duration) {
+	subscribers.mu.Lock()
+	delete(subscribers.subs, s)
+	subscribers.mu.Unlock()
+
+	s.drainOnce.Do(func() { close(s.draining) })
+	select {
+	case <-s.exited:
+	case <-clock.After(timeout):
+	}
+	s.Close()
+}
+
+// deliver calls fn with each batch of queued events until the subscription
+// is closed, or drained and its queue is empty.
+func (s *Subscription) deliver() {
+	defer close(s.exited)
+
+	batch := make([]Event, 0, s.batch)
+	for {
+		select {
+		case e := <-s.queue:
+			batch = append(batch[:0], e)
+		case <-s.done:
+			return
+		case <-s.draining:
+			select {
+			case e := <-s.queue:
+				batch = append(batch[:0], e)
+			default:
+				return
+			}
+		}
+	fill:
+		for len(batch) < s.batch {
+			select {
+			case e := <-s.queue:
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.fn(batch)
+		atomic.AddUint64(&s.delivered, uint64(len(batch)))
+	}
+}
+
+// publish queues e for every subscriber without blocking.
+func publish(e Event) {
+	e.RunID = RunID()
+	subscribers.mu.RLock()
+	defer subscribers.mu.RUnlock()
+
+	for s := range subscribers.subs {
+		select {
+		case s.queue <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			eventsDropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/uber-go/zap"
+)
+
+// ExitReason is why Serve returned.
+type ExitReason int
+
+const (
+	// ExitStopped is returned for a lifecycle stopped by a termination signal
+	// or request.
+	ExitStopped ExitReason = iota
+	// ExitUpgraded is returned for a lifecycle handed over to a new process.
+	ExitUpgraded
+	// ExitSetupFailed is returned when Serve failed before Init, such as on a
+	// pre-flight check, unmet requirement or invalid option. Restarting
+	// rarely helps.
+	ExitSetupFailed
+	// ExitInitFailed is returned when the lifecycle's Init failed, typically
+	// on invalid configuration.
+	ExitInitFailed
+	// ExitFailed is returned when the lifecycle's Start failed or the
+	// lifecycle failed while running. Restarting may help.
+	ExitFailed
+	// ExitStopFailed is returned for a lifecycle stopped by a termination
+	// signal or request whose Stop failed.
+	ExitStopFailed
+	// ExitMaxWork is returned for a lifecycle stopped once it had done the
+	// work set by WithMaxWork, to be restarted.
+	ExitMaxWork
+	// ExitLeaseLost is returned for a standby lifecycle stopped once it lost
+	// the lease of the fence set by WithFence, to be restarted in standby.
+	ExitLeaseLost
+)
+
+// String returns the name of the exit reason.
+func (r ExitReason) String() string {
+	switch r {
+	case ExitStopped:
+		return "stopped"
+	case ExitUpgraded:
+		return "upgraded"
+	case ExitSetupFailed:
+		return "setup failed"
+	case ExitInitFailed:
+		return "init failed"
+	case ExitFailed:
+		return "failed"
+	case ExitStopFailed:
+		return "stop failed"
+	case ExitMaxWork:
+		return "max work"
+	case ExitLeaseLost:
+		return "lease lost"
+	}
+	return fmt.Sprintf("ExitReason(%d)", int(r))
+}
+
+// WithExitCodes sets the exit codes Run exits with, and ExitCode returns, for
+// each exit reason, so supervisors can tell restart-worthy failures from
+// permanent ones, such as with systemd's RestartPreventExitStatus. Reasons
+// not in codes keep their default: 0 for ExitStopped, ExitUpgraded and
+// ExitMaxWork, and 1 otherwise.
+func WithExitCodes(codes map[ExitReason]int) Option {
+	return func(d *Dissembler) {
+		d.exitCodes = codes
+	}
+}
+
+// ExitReason returns why Serve returned.
+func (d *Dissembler) ExitReason() ExitReason {
+	return d.exitReason
+}
+
+// ExitCode returns the exit code for why Serve returned, as set by
+// WithExitCodes.
+func (d *Dissembler) ExitCode() int {
+	if code, ok := d.exitCodes[d.exitReason]; ok {
+		return code
+	}
+	switch d.exitReason {
+	case ExitStopped, ExitUpgraded, ExitMaxWork:
+		return 0
+	}
+	return 1
+}
+
+// Run serves the lifecycle, configured by opts, and then exits the process
+// with the exit code for why Serve returned.
+func Run(lc Lifecycle, opts ...Option) {
+	d := New(lc, opts...)
+	if err := d.Serve(); err != nil {
+		msgUnableToServe.emit(
+			log.String("reason", d.exitReason.String()),
+			log.String("error", err.Error()),
+		)
+	}
+	flushLogs()
+	os.Exit(d.ExitCode())
+}
@@ -0,0 +1,48 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+)
+
+// Fence acquires a lease shared by the members of an active/passive pair,
+// such as a lock in etcd, Consul or a database, blocking until it is held or
+// ctx is cancelled. It returns a channel closed once the lease is lost, such
+// as when it cannot be renewed during a network partition.
+type Fence func(ctx context.Context) (lost <-chan struct{}, err error)
+
+// WithFence guards the activation of a standby set up by WithStandby with
+// fence, so two members of a pair never both believe they are active, even
+// after a network partition. Once activated, the standby acquires the lease
+// before starting the lifecycle, and is not active until it does. Should the
+// lease be lost, it stops being active at once and is stopped as by SIGTERM,
+// with ExitLeaseLost, to be restarted in standby. Failing to acquire the
+// lease fails the lifecycle.
+//
+// The lease should outlive the stop of the lifecycle, as bounded by
+// WithStopBudget, so the other member does not acquire it while this one is
+// still stopping.
+func WithFence(fence Fence) Option {
+	return func(d *Dissembler) {
+		d.fence = fence
+	}
+}
+
+// watchLease deactivates and stops the lifecycle once lost is closed, unless
+// ctx is cancelled first.
+func (d *Dissembler) watchLease(ctx context.Context, lost <-chan struct{}) error {
+	select {
+	case <-lost:
+	case <-ctx.Done():
+		return nil
+	}
+	atomic.StoreInt32(&d.active, 0)
+	atomic.StoreInt32(&d.fenced, 1)
+	msgLeaseLost.emit()
+	d.request(context.Background(), syscall.SIGTERM)
+	return nil
+}
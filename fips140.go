@@ -0,0 +1,15 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build go1.24
+// +build go1.24
+
+package dissembler
+
+import "crypto/fips140"
+
+// fipsEnabled reports whether the Go Cryptographic Module runs in FIPS 140-3
+// mode.
+func fipsEnabled() bool {
+	return fips140.Enabled()
+}
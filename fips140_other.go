@@ -0,0 +1,12 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !go1.24
+// +build !go1.24
+
+package dissembler
+
+// fipsEnabled reports false before Go 1.24, which introduced FIPS 140-3 mode.
+func fipsEnabled() bool {
+	return false
+}
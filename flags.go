@@ -0,0 +1,44 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dissembler/dissembler/flags"
+	log "github.com/uber-go/zap"
+)
+
+// WithFlags sets the feature flags queried with flags.Enabled to those
+// returned by fn, which is called before Init and again on every reload, such
+// as flags.File to read them from a file. Changed flags are logged. If fn
+// fails before Init, Serve fails; if it fails on reload, the reload fails and
+// the current flags are kept.
+func WithFlags(fn func() (map[string]bool, error)) Option {
+	return func(d *Dissembler) {
+		d.flags = fn
+	}
+}
+
+// loadFlags loads the feature flags, if any.
+func (d *Dissembler) loadFlags() error {
+	if d.flags == nil {
+		return nil
+	}
+	f, err := d.flags()
+	if err != nil {
+		return fmt.Errorf("loading flags: %w", err)
+	}
+
+	changed := flags.Set(f)
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		msgFlagsChanged.emit(
+			log.String("flags", strings.Join(changed, ",")),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+// Package flags provides runtime feature toggles, enough to switch code paths
+// on and off without adopting a feature flag service.
+//
+// Flags are loaded with Dissembler's WithFlags option before Init and again on
+// every reload, typically from a file:
+//
+//	dissembler.Serve(lc, dissembler.WithFlags(flags.File("/etc/app/flags.json")))
+//
+//	if flags.Enabled("new-index") {
+//		...
+//	}
+//
+// Flags not set are disabled.
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	mu        sync.RWMutex
+	current   map[string]bool
+	callbacks = map[string][]func(enabled bool){}
+)
+
+// Enabled reports whether the flag name is enabled.
+func Enabled(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current[name]
+}
+
+// All returns the flags that are set, enabled or not.
+func All() map[string]bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make(map[string]bool, len(current))
+	for name, enabled := range current {
+		all[name] = enabled
+	}
+	return all
+}
+
+// OnChange registers fn to be called with the new value whenever the flag
+// name is enabled or disabled. fn is called synchronously from Set and must
+// not block.
+func OnChange(name string, fn func(enabled bool)) {
+	mu.Lock()
+	defer mu.Unlock()
+	cbs := callbacks[name]
+	callbacks[name] = append(cbs[:len(cbs):len(cbs)], fn)
+}
+
+// Set replaces the flags with flags, calling the OnChange callbacks of those
+// enabled or disabled by the change. It returns the names of the flags
+// changed.
+func Set(flags map[string]bool) []string {
+	next := make(map[string]bool, len(flags))
+	for name, enabled := range flags {
+		next[name] = enabled
+	}
+
+	mu.Lock()
+	prev := current
+	current = next
+	var changed []string
+	for name := range union(prev, next) {
+		if prev[name] != next[name] {
+			changed = append(changed, name)
+		}
+	}
+	cbs := make([][]func(bool), len(changed))
+	for i, name := range changed {
+		cbs[i] = callbacks[name]
+	}
+	mu.Unlock()
+
+	for i, name := range changed {
+		for _, fn := range cbs[i] {
+			fn(next[name])
+		}
+	}
+	return changed
+}
+
+// union returns the names set in either a or b.
+func union(a, b map[string]bool) map[string]struct{} {
+	names := make(map[string]struct{}, len(a)+len(b))
+	for name := range a {
+		names[name] = struct{}{}
+	}
+	for name := range b {
+		names[name] = struct{}{}
+	}
+	return names
+}
+
+// File returns a loader, for use with Dissembler's WithFlags, reading flags
+// from the JSON object in the file at path, such as:
+//
+//	{"new-index": true, "legacy-auth": false}
+func File(path string) func() (map[string]bool, error) {
+	return func() (map[string]bool, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var flags map[string]bool
+		if err := json.Unmarshal(b, &flags); err != nil {
+			return nil, fmt.Errorf("parsing flags in %s: %w", path, err)
+		}
+		return flags, nil
+	}
+}
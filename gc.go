@@ -0,0 +1,151 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/uber-go/zap"
+)
+
+// GCConfig tunes the garbage collector.
+type GCConfig struct {
+	// GCPercent is the garbage collection target percentage, as set by GOGC.
+	// Nil leaves it unchanged, and -1, like GOGC=off, disables collection.
+	GCPercent *int
+	// MemoryLimit is the soft memory limit in bytes, as set by GOMEMLIMIT.
+	// Zero leaves it unchanged and math.MaxInt64 removes the limit.
+	MemoryLimit int64
+}
+
+// gcPercentUnknown marks gcPercent as not yet read.
+const gcPercentUnknown = math.MinInt64
+
+// gcPercent is the garbage collection target percentage currently applied,
+// or gcPercentUnknown until tuneGC runs. The runtime offers no way to read it
+// without setting it, so it is only read when the collector is tuned.
+var gcPercent int64 = gcPercentUnknown
+
+func init() {
+	Metrics.Set(metricGC, expvar.Func(func() interface{} {
+		m := map[string]int64{
+			"memory_limit": debug.SetMemoryLimit(-1),
+		}
+		if p := atomic.LoadInt64(&gcPercent); p != gcPercentUnknown {
+			m["gc_percent"] = p
+		}
+		return m
+	}))
+}
+
+// readGCPercent returns the garbage collection target percentage currently
+// applied, reading it from the runtime the first time.
+func readGCPercent() int64 {
+	if p := atomic.LoadInt64(&gcPercent); p != gcPercentUnknown {
+		return p
+	}
+	p := debug.SetGCPercent(100)
+	debug.SetGCPercent(p)
+	atomic.StoreInt64(&gcPercent, int64(p))
+	return int64(p)
+}
+
+// WithGCConfig tunes the garbage collector with the configuration returned by
+// fn, which is called before Init and again on every reload so the settings
+// can follow configuration changes. The applied values are logged and
+// exported in Metrics as "gc". If fn fails before Init, Serve fails; if it
+// fails on reload, the reload fails and the current settings are kept.
+func WithGCConfig(fn func() (GCConfig, error)) Option {
+	return func(d *Dissembler) {
+		d.gcConfig = fn
+	}
+}
+
+// GCConfigFromEnv returns the GCConfig described by the GOGC and GOMEMLIMIT
+// environment variables, for use with WithGCConfig. GOGC may be "off", and
+// GOMEMLIMIT may be "off" or carry a B, KiB, MiB, GiB or TiB suffix.
+func GCConfigFromEnv() (GCConfig, error) {
+	var cfg GCConfig
+	switch v := os.Getenv("GOGC"); v {
+	case "":
+	case "off":
+		p := -1
+		cfg.GCPercent = &p
+	default:
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid GOGC %q", v)
+		}
+		cfg.GCPercent = &p
+	}
+
+	if v := os.Getenv("GOMEMLIMIT"); v != "" {
+		limit, err := parseMemoryLimit(v)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.MemoryLimit = limit
+	}
+	return cfg, nil
+}
+
+// parseMemoryLimit parses a memory limit in the syntax of GOMEMLIMIT.
+func parseMemoryLimit(s string) (int64, error) {
+	if s == "off" {
+		return math.MaxInt64, nil
+	}
+
+	units := []struct {
+		suffix string
+		shift  uint
+	}{{"TiB", 40}, {"GiB", 30}, {"MiB", 20}, {"KiB", 10}, {"B", 0}}
+	n, shift := s, uint(0)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			n, shift = strings.TrimSuffix(s, u.suffix), u.shift
+			break
+		}
+	}
+
+	v, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || v < 0 || v > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("invalid GOMEMLIMIT %q", s)
+	}
+	return v << shift, nil
+}
+
+// tuneGC applies the garbage collector configuration, if any.
+func (d *Dissembler) tuneGC() error {
+	if d.gcConfig == nil {
+		return nil
+	}
+	cfg, err := d.gcConfig()
+	if err != nil {
+		return fmt.Errorf("loading GC configuration: %w", err)
+	}
+	if cfg.MemoryLimit < 0 {
+		return fmt.Errorf("invalid memory limit %d", cfg.MemoryLimit)
+	}
+
+	if cfg.GCPercent != nil {
+		debug.SetGCPercent(*cfg.GCPercent)
+		atomic.StoreInt64(&gcPercent, int64(*cfg.GCPercent))
+	}
+	if cfg.MemoryLimit != 0 {
+		debug.SetMemoryLimit(cfg.MemoryLimit)
+	}
+
+	msgGarbageCollectorTuned.emit(
+		log.Int64("gc_percent", readGCPercent()),
+		log.Int64("memory_limit", debug.SetMemoryLimit(-1)),
+	)
+	return nil
+}
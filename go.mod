@@ -0,0 +1,11 @@
+module github.com/dissembler/dissembler
+
+go 1.21
+
+require github.com/uber-go/zap v0.1.0-beta.1
+
+require (
+	github.com/stretchr/testify v1.12.1 // indirect
+	github.com/uber-go/atomic v1.0.1-0.20161027163608-9e99152552a6 // indirect
+	go.uber.org/atomic v1.12.0 // indirect
+)
@@ -0,0 +1,285 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// GoroutineGrace bounds how long stopping waits, once the lifecycle has
+// stopped, for goroutines started with Go to return.
+const GoroutineGrace = 5 * time.Second
+
+// GoOption configures a goroutine started with Go.
+type GoOption func(*goroutine)
+
+// Weight sets the weight of the goroutine, such as the number of connections
+// to a shared resource it returns when it stops, counted against the limit
+// set by WithStopConcurrency. The default weight is 1.
+func Weight(w int64) GoOption {
+	return func(g *goroutine) {
+		g.weight = w
+	}
+}
+
+// WithStopConcurrency stops goroutines started with Go in waves rather than
+// all at once, so stopping thousands of workers does not stampede a shared
+// resource, such as a database receiving their connections back. Goroutines
+// are cancelled most recently started first, each wave cancelling as many as
+// fit within a total Weight of limit and waiting for them to return before
+// the next. Once GoroutineGrace has passed since the lifecycle stopped, any
+// goroutines left are cancelled at once.
+func WithStopConcurrency(limit int64) Option {
+	return func(d *Dissembler) {
+		d.stopConcurrency = limit
+	}
+}
+
+// Go runs fn in a goroutine named name on behalf of a component, in place of
+// a bare go statement, configured by opts. fn's context is cancelled once the
+// lifecycle begins stopping, or in turn if WithStopConcurrency is given, and
+// fn should then return. If fn returns an error, or panics, before then, the
+// lifecycle fails as if its Start had returned the error.
+//
+// Goroutines still running while the lifecycle stops are counted in the
+// drain progress, and once it has stopped they are given GoroutineGrace to
+// return; the names of those that do not are recorded in the ShutdownReport.
+func (d *Dissembler) Go(name string, fn func(ctx context.Context) error, opts ...GoOption) {
+	d.spawn(name, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("goroutine %s: %w", name, err)
+		}
+		return nil
+	}, opts...)
+}
+
+// spawn runs fn as a tracked goroutine, failing the lifecycle if it returns
+// an error or panics before shutdown begins.
+func (d *Dissembler) spawn(name string, fn func(ctx context.Context) error, opts ...GoOption) {
+	g := &goroutine{name: name, weight: 1, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(g)
+	}
+	parent := d.shutdown
+	if d.stopConcurrency > 0 {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	g.cancel = cancel
+	d.spawned.add(g, d.shutdown)
+
+	go func() {
+		defer d.spawned.done(g)
+
+		err := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("goroutine %s panicked: %v", name, r)
+					msgGoroutinePanicked.emit(
+						log.String("goroutine", name),
+						log.String("panic", fmt.Sprint(r)),
+						log.String("stack", string(debug.Stack())),
+					)
+				}
+			}()
+			return fn(ctx)
+		}()
+		if err == nil || d.shutdown.Err() != nil {
+			return
+		}
+		select {
+		case d.errc <- err:
+		default:
+		}
+	}()
+}
+
+// stopInWaves cancels the goroutines started with Go in waves within the
+// limit set by WithStopConcurrency.
+func (d *Dissembler) stopInWaves() {
+	running, abort := d.spawned.snapshot()
+	for len(running) > 0 {
+		var wave []*goroutine
+		var weight int64
+		for len(running) > 0 {
+			g := running[len(running)-1]
+			if len(wave) > 0 && weight+g.weight > d.stopConcurrency {
+				break
+			}
+			wave = append(wave, g)
+			weight += g.weight
+			running = running[:len(running)-1]
+		}
+
+		for _, g := range wave {
+			g.cancel()
+		}
+		for _, g := range wave {
+			select {
+			case <-g.done:
+			case <-abort:
+				return
+			}
+		}
+	}
+}
+
+// awaitGoroutines waits up to GoroutineGrace, or until ctx expires, for the
+// goroutines started with Go to return, returning the names of those still
+// running, which are then cancelled if they were waiting their turn.
+func (d *Dissembler) awaitGoroutines(ctx context.Context) []string {
+	t := clockOr(d.clock).NewTimer(GoroutineGrace)
+	defer t.Stop()
+	select {
+	case <-d.spawned.idle():
+		return nil
+	case <-t.C():
+	case <-ctx.Done():
+	}
+
+	d.spawned.cancelAll()
+	running := d.spawned.names()
+	if len(running) > 0 {
+		msgGoroutinesStillRunning.emit(
+			log.Int("goroutines", len(running)),
+			log.String("names", fmt.Sprint(running)),
+		)
+	}
+	return running
+}
+
+// goroutine is a goroutine started with Go.
+type goroutine struct {
+	name   string
+	weight int64
+	seq    uint64
+	cancel context.CancelFunc
+	// done is closed once the goroutine has returned.
+	done chan struct{}
+}
+
+// goroutineSet tracks the goroutines started with Go.
+type goroutineSet struct {
+	mu      sync.Mutex
+	seq     uint64
+	running map[*goroutine]struct{}
+	// idleC is closed once no goroutine is running, and abort once the
+	// remaining goroutines have been cancelled at once.
+	idleC   chan struct{}
+	abort   chan struct{}
+	aborted bool
+}
+
+// add records that g is running, cancelling it at once if shutdown has
+// already begun.
+func (s *goroutineSet) add(g *goroutine, shutdown context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		s.running = make(map[*goroutine]struct{})
+		s.abort = make(chan struct{})
+	}
+	if len(s.running) == 0 {
+		s.idleC = make(chan struct{})
+	}
+	s.seq++
+	g.seq = s.seq
+	s.running[g] = struct{}{}
+	if shutdown.Err() != nil {
+		g.cancel()
+	}
+}
+
+// done records that g has returned.
+func (s *goroutineSet) done(g *goroutine) {
+	g.cancel()
+	close(g.done)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, g)
+	if len(s.running) == 0 {
+		close(s.idleC)
+	}
+}
+
+// snapshot returns the goroutines running, in the order they were started,
+// and a channel closed once they are all cancelled by cancelAll.
+func (s *goroutineSet) snapshot() ([]*goroutine, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running := make([]*goroutine, 0, len(s.running))
+	for g := range s.running {
+		running = append(running, g)
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].seq < running[j].seq })
+	return running, s.abort
+}
+
+// cancelAll cancels every goroutine running.
+func (s *goroutineSet) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for g := range s.running {
+		g.cancel()
+	}
+	if s.abort != nil && !s.aborted {
+		close(s.abort)
+		s.aborted = true
+	}
+}
+
+// idle returns a channel closed once no goroutine is running.
+func (s *goroutineSet) idle() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.running) == 0 {
+		c := make(chan struct{})
+		close(c)
+		return c
+	}
+	return s.idleC
+}
+
+// len returns the number of goroutines running.
+func (s *goroutineSet) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.running)
+}
+
+// names returns the names of the goroutines running, sorted.
+func (s *goroutineSet) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.running))
+	for g := range s.running {
+		names = append(names, g.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// drainProgress reports the lifecycle's drain progress, if it implements
+// DrainReporter, counting the goroutines started with Go still running too.
+type drainProgress struct {
+	d *Dissembler
+	r DrainReporter
+}
+
+// DrainProgress returns the work remaining.
+func (p drainProgress) DrainProgress() int {
+	n := p.d.spawned.len()
+	if p.r != nil {
+		n += p.r.DrainProgress()
+	}
+	return n
+}
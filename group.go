@@ -0,0 +1,673 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// Group is a Lifecycle composed of named components. Components are
+// initialized and started in the order they were added and stopped in reverse
+// order. Each component is started in its own goroutine, so a component's
+// Start may block for as long as it runs.
+//
+// Each component has a restart policy, RestartNever unless declared with
+// Restart. A component that fails without being restarted fails the Group,
+// returning its error from Start, unless it is Optional, in which case it is
+// marked ComponentDegraded and retried.
+//
+// Group implements Reloader. On Reload the configuration is loaded again,
+// components that have become enabled are initialized and started, components
+// that have become disabled are stopped, and the remaining components that
+// implement Reloader are reloaded.
+//
+// Components may also be added and removed while the Group is running with
+// StartComponent and Remove, or per tenant listed in the configuration with
+// AddSharded.
+type Group struct {
+	// Config is called during Init and Reload to load the configuration passed
+	// to each component's EnabledFunc. If Config is nil, components receive a
+	// nil configuration.
+	Config func() (interface{}, error)
+	// ReadyPolicy selects how Ready aggregates the readiness of components.
+	// The default is ReadyAll.
+	ReadyPolicy ReadyPolicy
+	// Quorum is the number of ready components required under ReadyQuorum.
+	Quorum int
+	// Clock times restart backoffs and retries. If nil, the system clock is
+	// used.
+	Clock Clock
+
+	mu         sync.Mutex
+	components []*component
+	shards     []*shardSet
+	config     interface{}
+	ready      bool
+	started    bool
+	errc       chan error
+	// running counts the goroutines started by run, and idle is closed once
+	// it drops to zero while Start waits. Both are guarded by mu, so no
+	// goroutine is counted once Start has seen none running.
+	running int
+	idle    chan struct{}
+}
+
+// component is a named Lifecycle managed by a Group.
+type component struct {
+	name        string
+	lc          Lifecycle
+	enabled     func(cfg interface{}) bool
+	restart     RestartPolicy
+	backoff     Backoff
+	optional    bool
+	retry       time.Duration
+	initialized bool
+	// shard is the set the component belongs to, if added by AddSharded.
+	shard *shardSet
+	// rebuild and swapTimeout are set by Rebuild.
+	rebuild     func(cfg interface{}) (Lifecycle, error)
+	swapTimeout time.Duration
+	done        chan struct{}
+	quit        chan struct{}
+
+	// status holds the current *componentStatus, read without locking.
+	// Updates are serialized by mu, which also guards gen, the generation of
+	// the latest goroutine started by run.
+	mu     sync.Mutex
+	status atomic.Value
+	gen    uint64
+}
+
+// componentStatus is an immutable snapshot of a component's state.
+type componentStatus struct {
+	state ComponentState
+	err   error
+	since time.Time
+}
+
+// ComponentState is the state of a Group component.
+type ComponentState int
+
+const (
+	// ComponentStopped is a component that is not running.
+	ComponentStopped ComponentState = iota
+	// ComponentRunning is a component whose Start is running.
+	ComponentRunning
+	// ComponentDegraded is an optional component that failed and is awaiting
+	// its next retry.
+	ComponentDegraded
+	// ComponentExited is a component whose Start returned nil and that is
+	// not restarted, having completed its work. It counts as ready.
+	ComponentExited
+)
+
+// String returns the name of the component state.
+func (s ComponentState) String() string {
+	switch s {
+	case ComponentStopped:
+		return "stopped"
+	case ComponentRunning:
+		return "running"
+	case ComponentDegraded:
+		return "degraded"
+	case ComponentExited:
+		return "exited"
+	}
+	return fmt.Sprintf("ComponentState(%d)", int(s))
+}
+
+// ComponentStatus describes the state of a Group component.
+type ComponentStatus struct {
+	Name     string
+	State    ComponentState
+	Critical bool
+	// Since is when the component entered State.
+	Since time.Time
+	// Err is the error that degraded the component, if any.
+	Err error
+}
+
+// ComponentOption configures a component added to a Group.
+type ComponentOption func(*component)
+
+// EnabledFunc declares a function deciding, from the loaded configuration,
+// whether a component runs. It is evaluated during Init and on every Reload.
+// Components without an EnabledFunc are always enabled.
+func EnabledFunc(fn func(cfg interface{}) bool) ComponentOption {
+	return func(c *component) {
+		c.enabled = fn
+	}
+}
+
+// Optional marks a component as non-critical. When an optional component fails
+// and its restart policy does not restart it, the component is marked
+// ComponentDegraded instead of failing the Group, and its Start is retried
+// every retry interval, of at least MinRestartDelay, until it is stopped.
+func Optional(retry time.Duration) ComponentOption {
+	return func(c *component) {
+		c.optional = true
+		c.retry = retry
+		if c.retry < MinRestartDelay {
+			c.retry = MinRestartDelay
+		}
+	}
+}
+
+// Add appends a named component to the Group. Components added after the
+// Group has been initialized must use StartComponent instead.
+func (g *Group) Add(name string, lc Lifecycle, opts ...ComponentOption) {
+	c := &component{name: name, lc: lc}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	g.mu.Lock()
+	g.components = append(g.components, c)
+	g.mu.Unlock()
+}
+
+// Status returns the status of every component in the Group, in the order
+// they were added.
+func (g *Group) Status() []ComponentStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := make([]ComponentStatus, 0, len(g.components))
+	for _, c := range g.components {
+		cs := c.loadStatus()
+		status = append(status, ComponentStatus{
+			Name:     c.name,
+			State:    cs.state,
+			Critical: !c.optional,
+			Since:    cs.since,
+			Err:      cs.err,
+		})
+	}
+	return status
+}
+
+// setState records a component's state, keeping the degraded components
+// gauge current.
+func (c *component) setState(state ComponentState, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeState(state, err, now)
+}
+
+// setRunState records a component's state as set by the goroutine of
+// generation gen started by run, unless the component has since been run
+// again, so a superseded goroutine does not overwrite the state of its
+// successor.
+func (c *component) setRunState(gen uint64, state ComponentState, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.storeState(state, err, now)
+	}
+}
+
+// nextGen begins a new generation of the goroutine started by run.
+func (c *component) nextGen() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	return c.gen
+}
+
+// storeState records a component's state. c.mu must be held.
+func (c *component) storeState(state ComponentState, err error, now time.Time) {
+	prev := c.loadStatus()
+	switch {
+	case prev.state == ComponentDegraded && state != ComponentDegraded:
+		Metrics.Add(metricComponentsDegraded, -1)
+	case prev.state != ComponentDegraded && state == ComponentDegraded:
+		Metrics.Add(metricComponentsDegraded, 1)
+	}
+	since := prev.since
+	if prev.state != state {
+		since = now
+		publish(Event{
+			Type:           EventComponent,
+			Time:           now,
+			Component:      c.name,
+			ComponentState: state,
+			Err:            err,
+		})
+	}
+	c.status.Store(&componentStatus{state: state, err: err, since: since})
+}
+
+// loadStatus returns the component's current status without locking.
+func (c *component) loadStatus() *componentStatus {
+	if cs, ok := c.status.Load().(*componentStatus); ok {
+		return cs
+	}
+	return &stoppedStatus
+}
+
+// stoppedStatus is the status of a component whose state was never set.
+var stoppedStatus componentStatus
+
+// isEnabled reports whether the component should run under cfg.
+func (c *component) isEnabled(cfg interface{}) bool {
+	return c.enabled == nil || c.enabled(cfg)
+}
+
+// load calls the Group's Config function, if any.
+func (g *Group) load() (interface{}, error) {
+	if g.Config == nil {
+		return nil, nil
+	}
+	cfg, err := g.Config()
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// Init loads the configuration and initializes every enabled component.
+func (g *Group) Init() error {
+	if g.ReadyPolicy == ReadyQuorum && g.Quorum <= 0 {
+		return errQuorum
+	}
+	cfg, err := g.load()
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config = cfg
+	if g.errc == nil {
+		g.errc = make(chan error, 1)
+	}
+	g.ready = true
+	if err := g.reshard(cfg); err != nil {
+		return err
+	}
+	for _, c := range g.components {
+		if !c.isEnabled(cfg) {
+			msgComponentDisabled.emit(
+				log.String("component", c.name))
+			continue
+		}
+		if err := g.init(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts every initialized component. It returns the first error
+// returned by a component's Start, or nil once every component's Start has
+// returned.
+func (g *Group) Start() error {
+	g.mu.Lock()
+	g.started = true
+	for _, c := range g.components {
+		if c.initialized {
+			g.run(c)
+		}
+	}
+	idle := make(chan struct{})
+	if g.running == 0 {
+		close(idle)
+	} else {
+		g.idle = idle
+	}
+	g.mu.Unlock()
+
+	select {
+	case err := <-g.errc:
+		return err
+	case <-idle:
+		return nil
+	}
+}
+
+// Stop stops every initialized component in reverse order. All components are
+// stopped even if some fail; the first error is returned.
+func (g *Group) Stop() error {
+	return g.StopContext(context.Background())
+}
+
+// StopContext stops the Group as Stop does, passing ctx to components
+// implementing ContextStopper.
+func (g *Group) StopContext(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var first error
+	for i := len(g.components) - 1; i >= 0; i-- {
+		c := g.components[i]
+		if !c.initialized {
+			continue
+		}
+		if err := g.stopContext(ctx, c); err != nil && first == nil {
+			first = err
+		}
+	}
+	g.ready = false
+	g.started = false
+	return first
+}
+
+// StartComponent adds a named component to a Group that may already be
+// running. If the Group has been initialized the component is initialized
+// immediately, and if the Group has been started the component is started as
+// well. Component names must be unique within a Group.
+func (g *Group) StartComponent(ctx context.Context, name string, lc Lifecycle, opts ...ComponentOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c := &component{name: name, lc: lc}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.lookup(name) >= 0 {
+		return fmt.Errorf("component %s: already registered", name)
+	}
+	g.components = append(g.components, c)
+	if !g.ready || !c.isEnabled(g.config) {
+		return nil
+	}
+
+	if err := g.init(c); err != nil {
+		g.components = g.components[:len(g.components)-1]
+		return err
+	}
+	msgComponentAdded.emit(
+		log.String("component", name))
+	if g.started {
+		g.run(c)
+	}
+	return nil
+}
+
+// Remove stops a named component and removes it from the Group. It waits for
+// the component's Start to return until ctx is done.
+func (g *Group) Remove(ctx context.Context, name string) error {
+	g.mu.Lock()
+	i := g.lookup(name)
+	if i < 0 {
+		g.mu.Unlock()
+		return fmt.Errorf("component %s: not registered", name)
+	}
+	c := g.components[i]
+	g.components = append(g.components[:i], g.components[i+1:]...)
+
+	var err error
+	if c.initialized {
+		err = g.stop(c)
+	}
+	done := c.done
+	g.mu.Unlock()
+
+	msgComponentRemoved.emit(
+		log.String("component", name))
+	if err != nil {
+		return err
+	}
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lookup returns the index of the named component, or -1. The caller must hold
+// g.mu.
+func (g *Group) lookup(name string) int {
+	for i, c := range g.components {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reload loads the configuration again, adds and removes sharded components
+// for added and removed tenants, stops components that have become disabled,
+// initializes and starts components that have become enabled, swaps in
+// components rebuilt as declared with Rebuild, and reloads the remaining
+// running components implementing Reloader.
+//
+// A component failing to reload does not keep the others from reloading, and
+// nothing is rolled back: every component is reloaded, and Reload returns the
+// failures joined, each naming its component, so the caller can tell which
+// components applied the new configuration.
+func (g *Group) Reload() error {
+	cfg, err := g.load()
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config = cfg
+	if err := g.reshard(cfg); err != nil {
+		return err
+	}
+	var errs []error
+	for i := len(g.components) - 1; i >= 0; i-- {
+		c := g.components[i]
+		if c.initialized && !c.isEnabled(cfg) {
+			msgComponentDisabled.emit(
+				log.String("component", c.name))
+			if err := g.stop(c); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for i, c := range g.components {
+		switch {
+		case c.initialized:
+			if c.rebuild != nil {
+				lc, err := c.rebuild(cfg)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("component %s: rebuild: %w", c.name, err))
+					continue
+				}
+				if lc != nil {
+					if err := g.swap(i, c, lc); err != nil {
+						errs = append(errs, err)
+					}
+					continue
+				}
+			}
+			if r, ok := c.lc.(Reloader); ok {
+				clock := clockOr(g.Clock)
+				begin := clock.Now()
+				if err := r.Reload(); err != nil {
+					errs = append(errs, phaseError(c.name, PhaseReload, 1, clock.Now().Sub(begin), err))
+				}
+			}
+		case c.isEnabled(cfg):
+			msgComponentEnabled.emit(
+				log.String("component", c.name))
+			if err := g.init(c); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if g.started {
+				g.run(c)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// init initializes a component. The caller must hold g.mu.
+func (g *Group) init(c *component) error {
+	end := BootStep("component " + c.name + ": init")
+	defer end()
+
+	clock := clockOr(g.Clock)
+	begin := clock.Now()
+	if err := c.lc.Init(); err != nil {
+		return phaseError(c.name, PhaseInit, 1, clock.Now().Sub(begin), err)
+	}
+	c.initialized = true
+	return nil
+}
+
+// run starts a component in its own goroutine, restarting it according to its
+// restart policy until it is stopped. The caller must hold g.mu.
+func (g *Group) run(c *component) {
+	done, quit := make(chan struct{}), make(chan struct{})
+	c.done, c.quit = done, quit
+	gen := c.nextGen()
+	g.running++
+	go func() {
+		defer g.exited()
+		defer close(done)
+
+		clock := clockOr(g.Clock)
+		final := ComponentStopped
+		defer func() {
+			c.setRunState(gen, final, nil, clock.Now())
+		}()
+
+		failures, attempt := 0, 0
+		for {
+			attempt++
+			begin := clock.Now()
+			c.setRunState(gen, ComponentRunning, nil, begin)
+			err := c.lc.Start()
+			select {
+			case <-quit:
+				return
+			default:
+			}
+
+			if err != nil && failures > 0 && c.backoff.stable(failures, clock.Now().Sub(begin)) {
+				failures = 0
+			}
+
+			var delay time.Duration
+			switch {
+			case err == nil && c.restart != RestartAlways:
+				final = ComponentExited
+				return
+			case err == nil:
+				failures = 0
+				delay = c.backoff.delay(0)
+			case c.restart != RestartNever &&
+				(c.backoff.Attempts == 0 || failures < c.backoff.Attempts):
+				delay = c.backoff.delay(failures)
+				failures++
+				msgRestartingFailedComponent.emit(
+					log.String("component", c.name),
+					log.String("error", err.Error()),
+					log.Int("attempt", failures),
+					log.Duration("backoff", delay),
+				)
+			case c.optional:
+				failures = 0
+				delay = c.retry
+				c.setRunState(gen, ComponentDegraded, err, clock.Now())
+				msgComponentDegraded.emit(
+					log.String("component", c.name),
+					log.String("error", err.Error()),
+					log.Duration("retry", delay),
+				)
+			default:
+				g.fail(phaseError(c.name, PhaseStart, attempt, clock.Now().Sub(begin), err))
+				return
+			}
+
+			select {
+			case <-clock.After(delay):
+
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
+
+// exited records that a goroutine started by run has returned, waking Start
+// once none is running.
+func (g *Group) exited() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+	if g.running == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
+
+// fail reports a component failure to the Group's Start, or, once Start has
+// returned, to the receiver of Failures. Only the first failure is reported.
+func (g *Group) fail(err error) {
+	select {
+	case g.errc <- err:
+	default:
+	}
+}
+
+// Failures returns the channel on which the failure of a critical component
+// is delivered, so a failure after Start has returned, such as of a component
+// enabled by Reload or added by StartComponent, fails the lifecycle too. It
+// implements FailureNotifier.
+func (g *Group) Failures() <-chan error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.errc == nil {
+		g.errc = make(chan error, 1)
+	}
+	return g.errc
+}
+
+// stop stops a component. The caller must hold g.mu.
+func (g *Group) stop(c *component) error {
+	return g.stopContext(context.Background(), c)
+}
+
+// stopContext stops a component, passing ctx to it if it implements
+// ContextStopper. The caller must hold g.mu.
+func (g *Group) stopContext(ctx context.Context, c *component) error {
+	if !c.initialized {
+		violate("component %s stopped while not initialized", c.name)
+	}
+	c.initialized = false
+	if c.quit != nil {
+		close(c.quit)
+		c.quit = nil
+	}
+
+	clock := clockOr(g.Clock)
+	begin := clock.Now()
+	var err error
+	if s, ok := c.lc.(ContextStopper); ok {
+		err = s.StopContext(ctx)
+	} else {
+		err = c.lc.Stop()
+	}
+	if err != nil {
+		return phaseError(c.name, PhaseStop, 1, clock.Now().Sub(begin), err)
+	}
+	return nil
+}
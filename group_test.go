@@ -0,0 +1,67 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dissembler/dissembler"
+	"github.com/dissembler/dissembler/dissemblertest"
+	log "github.com/uber-go/zap"
+)
+
+// flaky is a component whose every Start runs until the test ends it.
+type flaky struct {
+	started chan struct{}
+	result  chan error
+}
+
+func (f *flaky) Init() error  { return nil }
+func (f *flaky) Start() error { f.started <- struct{}{}; return <-f.result }
+func (f *flaky) Stop() error  { return nil }
+
+// TestRestartOnFailureResetsAfterStableRun checks that a run lasting longer
+// than Backoff.Max ends a sequence of consecutive failures, so a component
+// failing now and then is not given up on.
+func TestRestartOnFailureResetsAfterStableRun(t *testing.T) {
+	previous := dissembler.Logger()
+	dissembler.SetLogger(log.New(log.NewJSONEncoder(), log.DiscardOutput))
+	defer dissembler.SetLogger(previous)
+
+	backoff := dissembler.Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Attempts: 2}
+	clock := dissemblertest.NewFakeClock(time.Unix(0, 0))
+	lc := &flaky{started: make(chan struct{}), result: make(chan error)}
+	g := &dissembler.Group{Clock: clock}
+	g.Add("flaky", lc, dissembler.Restart(dissembler.RestartOnFailure, backoff))
+	if err := g.Init(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- g.Start() }()
+
+	// Each run lasts the given time before failing; the run lasting longer
+	// than Max forgives the two failures before it.
+	runs := []time.Duration{0, 0, 2 * time.Second, 0, 0}
+	errBoom := errors.New("boom")
+	for i, lasts := range runs {
+		select {
+		case <-lc.started:
+		case err := <-done:
+			t.Fatalf("Start returned %v after %d runs, want %d", err, i, len(runs))
+		}
+		clock.Advance(lasts)
+		lc.result <- errBoom
+		if i == len(runs)-1 {
+			break
+		}
+		clock.BlockUntil(1)
+		clock.Advance(backoff.Max)
+	}
+
+	if err := <-done; !errors.Is(err, errBoom) {
+		t.Fatalf("Start returned %v, want %v", err, errBoom)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// DefaultHookBudget bounds how long an OnStop hook may run when no budget is
+// given with HookBudget.
+const DefaultHookBudget = 5 * time.Second
+
+// ErrHookBudget is reported for a hook that did not return within its
+// budget.
+var ErrHookBudget = errors.New("hook exceeded its budget")
+
+// hook is a function run once the lifecycle has stopped, or as the host
+// suspends or resumes.
+type hook struct {
+	name   string
+	fn     func(ctx context.Context) error
+	budget time.Duration
+}
+
+// HookOption configures an OnStop, OnSuspend or OnResume hook.
+type HookOption func(*hook)
+
+// HookBudget sets how long the hook may run. Its context is cancelled once the
+// budget is spent, and shutdown proceeds without waiting for it further.
+func HookBudget(d time.Duration) HookOption {
+	return func(h *hook) {
+		h.budget = d
+	}
+}
+
+// OnStop registers a named hook, such as flushing telemetry or deregistering
+// from service discovery, to run after the lifecycle stops. Hooks run in
+// reverse order of registration, each within its own budget so one slow hook
+// cannot consume the whole shutdown window, or all at once if
+// WithParallelHooks is given. Their outcomes and durations are recorded in the
+// ShutdownReport.
+func OnStop(name string, fn func(ctx context.Context) error, opts ...HookOption) Option {
+	return func(d *Dissembler) {
+		d.hooks = append(d.hooks, newHook(name, fn, opts))
+	}
+}
+
+// newHook returns a hook configured by opts.
+func newHook(name string, fn func(ctx context.Context) error, opts []HookOption) hook {
+	h := hook{name: name, fn: fn, budget: DefaultHookBudget}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	return h
+}
+
+// WithParallelHooks runs OnStop hooks concurrently rather than one after
+// another.
+func WithParallelHooks() Option {
+	return func(d *Dissembler) {
+		d.parallelHooks = true
+	}
+}
+
+// HookResult is the outcome of an OnStop hook.
+type HookResult struct {
+	Name     string
+	Duration time.Duration
+	// Err is the error returned by the hook, or ErrHookBudget if it did not
+	// return within its budget.
+	Err error
+}
+
+// ShutdownReport describes how the lifecycle stopped.
+type ShutdownReport struct {
+	// Started is when stopping began.
+	Started time.Time
+	// Duration is how long stopping took, including hooks.
+	Duration time.Duration
+	// StopDuration is how long the lifecycle's Stop took.
+	StopDuration time.Duration
+	// StopErr is the error returned by the lifecycle's Stop.
+	StopErr error
+	// Goroutines are the names of goroutines started with Go still running
+	// once the lifecycle had stopped and GoroutineGrace had passed.
+	Goroutines []string
+	// Hooks are the outcomes of the OnStop hooks, in the order they were run,
+	// or registered if they ran in parallel.
+	Hooks []HookResult
+	// Leaked are the stacks of goroutines started while serving and still
+	// running once Serve stopped, if checked with WithLeakCheck.
+	Leaked []string
+}
+
+// ShutdownReport returns the report of the most recent shutdown, or the zero
+// ShutdownReport if the lifecycle has not stopped.
+func (d *Dissembler) ShutdownReport() ShutdownReport {
+	d.reportMu.Lock()
+	defer d.reportMu.Unlock()
+	return d.report
+}
+
+// runHooks runs the OnStop hooks, returning their outcomes.
+func (d *Dissembler) runHooks() []HookResult {
+	if atomic.LoadInt32(&d.stopped) == 0 {
+		violate("OnStop hooks run before the lifecycle stopped")
+	}
+	if s := d.State(); s != StateStopping {
+		violate("OnStop hooks run in state %s", s)
+	}
+	results := make([]HookResult, len(d.hooks))
+	if !d.parallelHooks {
+		for i := range d.hooks {
+			h := d.hooks[len(d.hooks)-1-i]
+			results[i] = d.runHook(msgStopHookFailed, h)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, h := range d.hooks {
+		wg.Add(1)
+		go func(i int, h hook) {
+			defer wg.Done()
+			results[i] = d.runHook(msgStopHookFailed, h)
+		}(i, h)
+	}
+	wg.Wait()
+	return results
+}
+
+// runHook runs h, an OnStop, OnSuspend or OnResume hook, within its budget,
+// logging failed if it fails.
+func (d *Dissembler) runHook(failed Message, h hook) HookResult {
+	clock := clockOr(d.clock)
+	ctx, cancel := withTimeout(context.Background(), clock, h.budget)
+	defer cancel()
+
+	start := clock.Now()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ErrHookBudget
+	}
+
+	r := HookResult{Name: h.name, Duration: clock.Now().Sub(start), Err: err}
+	if err != nil {
+		failed.emit(
+			log.String("hook", h.name),
+			log.Duration("duration", r.Duration),
+			log.String("error", err.Error()),
+		)
+	}
+	return r
+}
+
+// logReport logs the shutdown report.
+func logReport(r ShutdownReport) {
+	fields := make([]log.Field, 0, 4+len(r.Hooks))
+	fields = append(fields,
+		log.Duration("duration", r.Duration),
+		log.Duration("stop_duration", r.StopDuration),
+		log.Bool("stop_failed", r.StopErr != nil),
+		log.Int("goroutines_running", len(r.Goroutines)),
+	)
+	for _, h := range r.Hooks {
+		fields = append(fields, log.Duration("hook_"+h.Name, h.Duration))
+	}
+	msgShutdownReport.emit(fields...)
+}
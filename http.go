@@ -0,0 +1,191 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long an HTTPServer waits for in-flight
+// requests when stopping if ShutdownTimeout is not set, and how long a Server
+// is given to shut down.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// HTTPServer adapts an http.Server to the Lifecycle interface. Init binds the
+// server's address, or every address in Addrs, through Listen, so the
+// listeners survive upgrades, Start serves on all of them until the server is
+// shut down, and Stop shuts the server down gracefully, waiting for in-flight
+// requests on every listener.
+//
+// HTTPServer counts in-flight requests and implements DrainReporter, so their
+// number is reported while stopping, and counts requests served, implementing
+// WorkCounter. Connections leaving the request cycle,
+// such as WebSockets, are not covered by graceful shutdown; handlers register
+// them with TrackLongLived to have them ended according to LongLived.
+type HTTPServer struct {
+	// Server is the server to run. Its handler is wrapped with Middleware
+	// during Init.
+	Server *http.Server
+	// Addrs are the addresses to serve on, such as HTTP and HTTPS, IPv4 and
+	// IPv6, or TCP and a Unix socket. Addresses marked TLS are served with the
+	// server's TLSConfig. If empty, the server's Addr is served, with TLS if
+	// TLSConfig is set.
+	Addrs []ListenAddr
+	// ShutdownTimeout bounds how long Stop waits for in-flight requests. If
+	// zero, DefaultShutdownTimeout is used.
+	ShutdownTimeout time.Duration
+	// RejectWhileDraining makes requests arriving on open connections after
+	// Stop has begun receive 503 Service Unavailable instead of being served.
+	RejectWhileDraining bool
+	// LongLived determines how connections registered with TrackLongLived are
+	// ended while stopping. By default they are closed immediately.
+	LongLived LongLivedPolicy
+	// Clock times the shutdown and long-lived connection grace periods. If
+	// nil, the system clock is used.
+	Clock Clock
+
+	lns       []net.Listener
+	tls       []bool
+	inflight  int64
+	served    int64
+	draining  int32
+	longLived longLivedConns
+}
+
+// NewHTTPServer returns an HTTPServer running srv.
+func NewHTTPServer(srv *http.Server) *HTTPServer {
+	return &HTTPServer{Server: srv}
+}
+
+// Init wraps the server's handler with Middleware and binds its addresses.
+func (h *HTTPServer) Init() error {
+	handler := h.Server.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	h.Server.Handler = h.Middleware(handler)
+
+	addrs := h.Addrs
+	if len(addrs) == 0 {
+		addr := h.Server.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		addrs = []ListenAddr{{Address: addr, TLS: h.Server.TLSConfig != nil}}
+	}
+	lns, err := ListenAll(addrs...)
+	if err != nil {
+		return err
+	}
+	h.lns = lns
+	h.tls = make([]bool, len(addrs))
+	for i, a := range addrs {
+		h.tls[i] = a.TLS
+	}
+	return nil
+}
+
+// Start serves requests on every listener until the server is shut down. If
+// serving on one listener fails, the others are closed and the error is
+// returned.
+func (h *HTTPServer) Start() error {
+	errc := make(chan error, len(h.lns))
+	for i, ln := range h.lns {
+		go func(ln net.Listener, tls bool) {
+			var err error
+			if tls {
+				err = h.Server.ServeTLS(ln, "", "")
+			} else {
+				err = h.Server.Serve(ln)
+			}
+			errc <- err
+		}(ln, h.tls[i])
+	}
+
+	var first error
+	for range h.lns {
+		err := <-errc
+		if err == http.ErrServerClosed || first != nil {
+			continue
+		}
+		first = err
+		for _, ln := range h.lns {
+			ln.Close()
+		}
+	}
+	return first
+}
+
+// Listeners returns the listeners bound by Init, in the order of Addrs.
+func (h *HTTPServer) Listeners() []net.Listener {
+	return h.lns
+}
+
+// Stop gracefully shuts the server down, waiting up to ShutdownTimeout for
+// in-flight requests to complete and long-lived connections to end.
+func (h *HTTPServer) Stop() error {
+	return h.StopContext(context.Background())
+}
+
+// StopContext stops the server as Stop does, but by the time ctx expires at
+// the latest, such as when the stop budget set by WithStopBudget is spent.
+func (h *HTTPServer) StopContext(ctx context.Context) error {
+	atomic.StoreInt32(&h.draining, 1)
+
+	timeout := h.ShutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	clock := clockOr(h.Clock)
+	ctx, cancel := withTimeout(ctx, clock, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		h.longLived.drain(ctx, clock, h.LongLived)
+		close(done)
+	}()
+	err := h.Server.Shutdown(ctx)
+	<-done
+	return err
+}
+
+// TrackLongLived registers a long-lived connection, such as an upgraded
+// WebSocket, to be ended according to LongLived when the server stops. The
+// returned function must be called once the connection has ended.
+func (h *HTTPServer) TrackLongLived(c LongLivedConnHandler) (done func()) {
+	return h.longLived.track(c)
+}
+
+// DrainProgress returns the number of requests in flight and long-lived
+// connections open.
+func (h *HTTPServer) DrainProgress() int {
+	return int(atomic.LoadInt64(&h.inflight)) + h.longLived.len()
+}
+
+// Middleware counts the requests in flight through next. Once the server is
+// draining, responses carry Connection: close so clients reconnect elsewhere,
+// and if RejectWhileDraining is set, requests are refused with 503 Service
+// Unavailable.
+func (h *HTTPServer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&h.draining) == 1 {
+			w.Header().Set("Connection", "close")
+			if h.RejectWhileDraining {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable),
+					http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		atomic.AddInt64(&h.inflight, 1)
+		defer atomic.AddInt64(&h.inflight, -1)
+		defer atomic.AddInt64(&h.served, 1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// incarnationFile is the name of the state file counting incarnations in the
+// runtime directory.
+const incarnationFile = "incarnation"
+
+// Incarnation returns how many times the service has started on this host,
+// including this start, counted in a state file in the runtime directory set
+// up by WithRuntimeDir. A start by an upgrade counts. It is 0 if there is no
+// runtime directory or the count could not be kept.
+//
+// The count lasts as long as the runtime directory, which Dissembler removes
+// once the lifecycle has stopped if it created it, so a rising count reveals a
+// crash loop that a supervisor restarting the process hides. Under systemd,
+// RuntimeDirectoryPreserve=restart keeps the directory across restarts.
+func (d *Dissembler) Incarnation() uint64 {
+	return d.incarnation
+}
+
+// countIncarnation increments the incarnation count in the runtime directory,
+// logging and exporting it.
+func (d *Dissembler) countIncarnation() {
+	if d.runtimeDir == "" {
+		return
+	}
+	path := filepath.Join(d.runtimeDir, incarnationFile)
+	var n uint64
+	if b, err := os.ReadFile(path); err == nil {
+		n, _ = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	}
+	n++
+	if err := WriteFileAtomic(path, []byte(strconv.FormatUint(n, 10)+"\n"), 0640); err != nil {
+		msgUnableToCountIncarnation.emit(
+			log.String("path", path),
+			log.String("error", err.Error()),
+		)
+		return
+	}
+
+	d.incarnation = n
+	msgIncarnation.emit(
+		log.Int64("incarnation", int64(n)),
+	)
+	Metrics.Set(metricIncarnation, expvar.Func(func() interface{} {
+		return n
+	}))
+}
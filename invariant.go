@@ -0,0 +1,78 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// violations are the invariant violations recorded since CheckInvariants was
+// last called.
+var violations struct {
+	sync.Mutex
+	list []string
+}
+
+// violate records a violation of an invariant.
+func violate(format string, args ...interface{}) {
+	violations.Lock()
+	defer violations.Unlock()
+	violations.list = append(violations.list, fmt.Sprintf(format, args...))
+}
+
+// recordedViolations returns the invariant violations recorded since
+// CheckInvariants was last called, leaving them recorded.
+func recordedViolations() []string {
+	violations.Lock()
+	defer violations.Unlock()
+	return append([]string(nil), violations.list...)
+}
+
+// CheckInvariants returns an error describing every violation of the
+// lifecycle contract recorded in the process since it was last called, or nil
+// if there was none. Dissembler and Group check, as they run, that:
+//
+//   - a Dissembler only moves between states as LegalTransition allows;
+//   - the lifecycle is stopped at most once each time it is served;
+//   - OnStop hooks run only once the lifecycle has stopped, while stopping;
+//   - a Group component is only stopped once each time it is initialized.
+//
+// The checks are cheap and always on, so tests and fuzzers driving lifecycles,
+// including custom middleware and supervisors built on Group, can call it
+// once they are done to validate them against the contract.
+func CheckInvariants() error {
+	violations.Lock()
+	defer violations.Unlock()
+	if len(violations.list) == 0 {
+		return nil
+	}
+	err := errors.New("invariants violated: " + strings.Join(violations.list, "; "))
+	violations.list = nil
+	return err
+}
+
+// legalTransitions are the states a Dissembler may move to from each state.
+var legalTransitions = map[State][]State{
+	StateIdle:      {StateStarting},
+	StateStarting:  {StateRunning, StateReloading, StateStopping, StateStopped},
+	StateRunning:   {StateReloading, StateUpgrading, StateStopping, StateStopped},
+	StateReloading: {StateRunning, StateStarting},
+	StateUpgrading: {StateRunning, StateStarting},
+	StateStopping:  {StateStopped},
+	StateStopped:   {StateStarting},
+}
+
+// LegalTransition reports whether a Dissembler may move from state from to
+// state to.
+func LegalTransition(from, to State) bool {
+	for _, s := range legalTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+// Package kafkasink provides a dissembler.EventSink producing lifecycle events
+// to a Kafka topic partition as JSON, speaking the Kafka wire protocol
+// directly so no client library is required.
+//
+//	sink := kafkasink.New("kafka-0.internal:9092", "dissembler.events")
+//	defer sink.Close()
+//	dissembler.Serve(lc, dissembler.WithEventSink(sink))
+//
+// The sink does not discover the cluster: Addr must be the broker leading
+// Partition of Topic. Batches are sent uncompressed with acks=1.
+package kafkasink
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dissembler/dissembler"
+)
+
+// DefaultTimeout bounds connecting and producing when Timeout is not set.
+const DefaultTimeout = 5 * time.Second
+
+const (
+	// apiProduce and produceVersion select Produce v3, the oldest version
+	// carrying v2 record batches, which brokers since Kafka 0.11 accept.
+	apiProduce     = 0
+	produceVersion = 3
+	// clientID identifies the sink to the broker.
+	clientID = "dissembler"
+)
+
+// castagnoli is the CRC table record batches are checksummed with.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Sink produces each event as a JSON record on Partition of Topic. It
+// connects on the first Send and reconnects on the Send following a failure.
+type Sink struct {
+	// Addr is the address of the broker leading Partition, such as
+	// "localhost:9092".
+	Addr string
+	// Topic is the topic events are produced to.
+	Topic string
+	// Partition is the partition of Topic events are produced to.
+	Partition int32
+	// Timeout bounds connecting and producing each batch. If zero,
+	// DefaultTimeout is used.
+	Timeout time.Duration
+
+	mu          sync.Mutex
+	conn        net.Conn
+	r           *bufio.Reader
+	correlation int32
+}
+
+// New returns a Sink producing to partition 0 of topic at the broker addr.
+func New(addr, topic string) *Sink {
+	return &Sink{Addr: addr, Topic: topic}
+}
+
+// Send produces events as a single record batch, waiting for the partition
+// leader to acknowledge it.
+func (s *Sink) Send(events []dissembler.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch, err := recordBatch(events)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	s.conn.SetDeadline(time.Now().Add(s.timeout()))
+
+	s.correlation++
+	if err := s.produce(s.correlation, batch); err != nil {
+		s.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the broker.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+// closeLocked closes the connection, if any. s.mu must be held.
+func (s *Sink) closeLocked() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.r = nil, nil
+	return err
+}
+
+// connect dials the broker. s.mu must be held.
+func (s *Sink) connect() error {
+	if s.Topic == "" || len(s.Topic) > 249 {
+		return fmt.Errorf("invalid Kafka topic %q", s.Topic)
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Addr, s.timeout())
+	if err != nil {
+		return err
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+	return nil
+}
+
+// produce sends a Produce request carrying batch and reads its response.
+// s.mu must be held.
+func (s *Sink) produce(correlation int32, batch []byte) error {
+	var b buffer
+	b.int32(0) // size, set below
+	b.int16(apiProduce)
+	b.int16(produceVersion)
+	b.int32(correlation)
+	b.string(clientID)
+	b.int16(-1) // transactional_id: null
+	b.int16(1)  // acks: leader only
+	b.int32(int32(s.timeout() / time.Millisecond))
+	b.int32(1) // topics
+	b.string(s.Topic)
+	b.int32(1) // partitions
+	b.int32(s.Partition)
+	b.int32(int32(len(batch)))
+	b = append(b, batch...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+
+	if _, err := s.conn.Write(b); err != nil {
+		return err
+	}
+	return s.readResponse(correlation)
+}
+
+// readResponse reads the Produce response to correlation, returning the
+// error reported for the partition, if any. s.mu must be held.
+func (s *Sink) readResponse(correlation int32) error {
+	var size int32
+	if err := binary.Read(s.r, binary.BigEndian, &size); err != nil {
+		return err
+	}
+	if size < 4 || size > 1<<20 {
+		return fmt.Errorf("kafka: invalid response size %d", size)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(s.r, buf); err != nil {
+		return err
+	}
+
+	r := reader{buf: buf}
+	if got := r.int32(); got != correlation {
+		return fmt.Errorf("kafka: response to request %d, want %d", got, correlation)
+	}
+	for topics := r.int32(); topics > 0; topics-- {
+		r.string()
+		for partitions := r.int32(); partitions > 0; partitions-- {
+			partition, code := r.int32(), r.int16()
+			r.int64() // base_offset
+			r.int64() // log_append_time
+			if code != 0 && r.err == nil {
+				return fmt.Errorf("kafka: producing to %s/%d failed with error code %d",
+					s.Topic, partition, code)
+			}
+		}
+	}
+	return r.err
+}
+
+// timeout returns Timeout, or DefaultTimeout if it is not set.
+func (s *Sink) timeout() time.Duration {
+	if s.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return s.Timeout
+}
+
+// recordBatch encodes events as a v2 record batch, one record each, with a
+// null key and the event as the JSON value.
+func recordBatch(events []dissembler.Event) ([]byte, error) {
+	first := events[0].Time.UnixMilli()
+	last := first
+
+	var records buffer
+	for i, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		ts := e.Time.UnixMilli()
+		if ts > last {
+			last = ts
+		}
+
+		var rec buffer
+		rec = append(rec, 0) // attributes
+		rec.varint(ts - first)
+		rec.varint(int64(i))
+		rec.varint(-1) // key: null
+		rec.varint(int64(len(value)))
+		rec = append(rec, value...)
+		rec.varint(0) // headers
+
+		records.varint(int64(len(rec)))
+		records = append(records, rec...)
+	}
+
+	var b buffer
+	b.int64(0) // base_offset
+	b.int32(0) // batch_length, set below
+	b.int32(-1)
+	b = append(b, 2) // magic
+	b.int32(0)       // crc, set below
+	crcFrom := len(b)
+	b.int16(0) // attributes: uncompressed, CreateTime
+	b.int32(int32(len(events) - 1))
+	b.int64(first)
+	b.int64(last)
+	b.int64(-1) // producer_id
+	b.int16(-1) // producer_epoch
+	b.int32(-1) // base_sequence
+	b.int32(int32(len(events)))
+	b = append(b, records...)
+
+	binary.BigEndian.PutUint32(b[8:], uint32(len(b)-12))
+	binary.BigEndian.PutUint32(b[crcFrom-4:], crc32.Checksum(b[crcFrom:], castagnoli))
+	return b, nil
+}
+
+// buffer appends the primitive types of the Kafka protocol.
+type buffer []byte
+
+func (b *buffer) int16(v int16)  { *b = binary.BigEndian.AppendUint16(*b, uint16(v)) }
+func (b *buffer) int32(v int32)  { *b = binary.BigEndian.AppendUint32(*b, uint32(v)) }
+func (b *buffer) int64(v int64)  { *b = binary.BigEndian.AppendUint64(*b, uint64(v)) }
+func (b *buffer) varint(v int64) { *b = binary.AppendVarint(*b, v) }
+
+func (b *buffer) string(s string) {
+	b.int16(int16(len(s)))
+	*b = append(*b, s...)
+}
+
+// reader reads the primitive types of the Kafka protocol, recording the
+// first error and returning zero values once buf is exhausted.
+type reader struct {
+	buf []byte
+	err error
+}
+
+// errShort is the error of a response ending early.
+var errShort = errors.New("kafka: short response")
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		if r.err == nil {
+			r.err = errShort
+		}
+		return make([]byte, 8)
+	}
+	p := r.buf[:n]
+	r.buf = r.buf[n:]
+	return p
+}
+
+func (r *reader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *reader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *reader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+func (r *reader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/uber-go/zap"
+)
+
+// errLandlockUnsupported is returned where the kernel does not support
+// Landlock.
+var errLandlockUnsupported = errors.New("landlock not supported by the kernel")
+
+// LandlockRule grants access to a file or directory tree.
+type LandlockRule struct {
+	Path string
+	// Write grants creating, writing, truncating, renaming and removing, in
+	// addition to reading and executing.
+	Write bool
+}
+
+// ReadOnly returns rules granting read access to paths, such as a
+// configuration directory.
+func ReadOnly(paths ...string) []LandlockRule {
+	rules := make([]LandlockRule, len(paths))
+	for i, path := range paths {
+		rules[i] = LandlockRule{Path: path}
+	}
+	return rules
+}
+
+// ReadWrite returns rules granting read and write access to paths, such as
+// data and log directories.
+func ReadWrite(paths ...string) []LandlockRule {
+	rules := make([]LandlockRule, len(paths))
+	for i, path := range paths {
+		rules[i] = LandlockRule{Path: path, Write: true}
+	}
+	return rules
+}
+
+// WithLandlock restricts the filesystem access of the process to the paths
+// granted by rules once Init has returned, using Landlock, so a compromised
+// daemon cannot read or write files beyond its own:
+//
+//	dissembler.WithLandlock(append(
+//		dissembler.ReadOnly("/etc/app", "/etc/resolv.conf", "/etc/hosts"),
+//		dissembler.ReadWrite("/var/lib/app", "/var/log/app")...)...)
+//
+// Files Dissembler itself uses after Init, such as the runtime directory and
+// the binary re-executed on upgrade, must be granted too. The restriction
+// cannot be lifted and is inherited across upgrades. It also sets
+// no_new_privs. Landlock requires Linux 5.13; on kernels without it a
+// warning is logged and Serve proceeds unrestricted. Since it applies to each
+// thread, like WithUnshare it requires a binary built without cgo.
+func WithLandlock(rules ...LandlockRule) Option {
+	return func(d *Dissembler) {
+		d.landlock = append(d.landlock, rules...)
+	}
+}
+
+// applyLandlock applies WithLandlock.
+func (d *Dissembler) applyLandlock() error {
+	if len(d.landlock) == 0 {
+		return nil
+	}
+	abi, err := landlock(d.landlock)
+	if errors.Is(err, errLandlockUnsupported) {
+		msgUnableToRestrictFiles.emit(
+			log.String("error", err.Error()),
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("restricting filesystem access: %w", err)
+	}
+	msgFilesRestricted.emit(
+		log.Int("landlock_abi", abi),
+		log.Int("rules", len(d.landlock)),
+	)
+	return nil
+}
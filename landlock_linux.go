@@ -0,0 +1,132 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock syscalls, numbered alike on every architecture.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+)
+
+const (
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+	// oPath is O_PATH, which the syscall package lacks.
+	oPath = 0x200000
+)
+
+// Landlock filesystem access rights.
+const (
+	landlockExecute    = 1 << 0
+	landlockWriteFile  = 1 << 1
+	landlockReadFile   = 1 << 2
+	landlockReadDir    = 1 << 3
+	landlockRemoveDir  = 1 << 4
+	landlockRemoveFile = 1 << 5
+	landlockMakeChar   = 1 << 6
+	landlockMakeDir    = 1 << 7
+	landlockMakeReg    = 1 << 8
+	landlockMakeSock   = 1 << 9
+	landlockMakeFifo   = 1 << 10
+	landlockMakeBlock  = 1 << 11
+	landlockMakeSym    = 1 << 12
+	landlockRefer      = 1 << 13
+	landlockTruncate   = 1 << 14
+	landlockIoctlDev   = 1 << 15
+
+	// landlockFileRights are the rights applicable to files rather than
+	// directories.
+	landlockFileRights = landlockExecute | landlockWriteFile | landlockReadFile |
+		landlockTruncate | landlockIoctlDev
+	// landlockReadRights are granted by every rule.
+	landlockReadRights = landlockExecute | landlockReadFile | landlockReadDir
+)
+
+// landlock restricts every thread to rules, returning the Landlock ABI
+// version used.
+func landlock(rules []LandlockRule) (int, error) {
+	abi, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno == syscall.ENOSYS || errno == syscall.EOPNOTSUPP {
+		return 0, errLandlockUnsupported
+	}
+	if errno != 0 {
+		return 0, os.NewSyscallError("landlock_create_ruleset", errno)
+	}
+
+	// Rights introduced by later ABI versions are handled only if the kernel
+	// knows them.
+	var handled uint64 = landlockMakeSym<<1 - 1
+	if abi >= 2 {
+		handled |= landlockRefer
+	}
+	if abi >= 3 {
+		handled |= landlockTruncate
+	}
+	if abi >= 5 {
+		handled |= landlockIoctlDev
+	}
+
+	attr := handled
+	fd, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return 0, os.NewSyscallError("landlock_create_ruleset", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, r := range rules {
+		if err := landlockAddRule(int(fd), r, handled); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return 0, allThreadsError("prctl", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return 0, allThreadsError("landlock_restrict_self", errno)
+	}
+	return int(abi), nil
+}
+
+// landlockAddRule adds the rule for r to the ruleset.
+func landlockAddRule(ruleset int, r LandlockRule, handled uint64) error {
+	fd, err := syscall.Open(r.Path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: r.Path, Err: err}
+	}
+	defer syscall.Close(fd)
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "stat", Path: r.Path, Err: err}
+	}
+
+	access := uint64(landlockReadRights)
+	if r.Write {
+		access = handled
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= landlockFileRights
+	}
+	access &= handled
+
+	// struct landlock_path_beneath_attr is packed: a 64-bit access mask
+	// followed by a 32-bit file descriptor.
+	var attr [12]byte
+	binary.NativeEndian.PutUint64(attr[:], access)
+	binary.NativeEndian.PutUint32(attr[8:], uint32(fd))
+	if _, _, errno := syscall.RawSyscall6(sysLandlockAddRule, uintptr(ruleset),
+		landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr[0])), 0, 0, 0); errno != 0 {
+		return &os.PathError{Op: "landlock_add_rule", Path: r.Path, Err: errno}
+	}
+	return nil
+}
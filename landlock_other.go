@@ -0,0 +1,12 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+// landlock is not supported outside Linux.
+func landlock(rules []LandlockRule) (int, error) {
+	return 0, errLandlockUnsupported
+}
@@ -0,0 +1,126 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+const (
+	// DefaultLeakGrace is how long goroutines are given to exit once Serve
+	// has stopped, if WithLeakCheck is given no grace period.
+	DefaultLeakGrace = time.Second
+
+	// leakPoll is how often goroutines are checked during the grace period.
+	leakPoll = 10 * time.Millisecond
+)
+
+// leakIgnored are functions whose goroutines outlive Serve by design.
+var leakIgnored = []string{
+	"dissembler/dissembler.startAsyncLogs",
+	"dissembler/dissembler.(*Dissembler).awaitReady",
+	"os/signal.signal_recv",
+	"os/signal.loop",
+}
+
+// WithLeakCheck snapshots the running goroutines before the lifecycle starts
+// and, once Serve has stopped everything, reports goroutines started since
+// that are still running after grace, logging their stacks and recording them
+// in the ShutdownReport. A grace of zero means DefaultLeakGrace. It suits
+// tests and canary environments, where leaks should be caught before they
+// accumulate in long-running processes.
+func WithLeakCheck(grace time.Duration) Option {
+	return func(d *Dissembler) {
+		if grace <= 0 {
+			grace = DefaultLeakGrace
+		}
+		d.leakGrace = grace
+	}
+}
+
+// snapshotGoroutines records the running goroutines, if checking for leaks.
+func (d *Dissembler) snapshotGoroutines() {
+	if d.leakGrace == 0 {
+		return
+	}
+	d.goroutines = make(map[string]bool)
+	for id := range goroutineStacks() {
+		d.goroutines[id] = true
+	}
+}
+
+// checkLeaks reports goroutines started since snapshotGoroutines that are
+// still running once the grace period is over.
+func (d *Dissembler) checkLeaks() {
+	if d.goroutines == nil {
+		return
+	}
+	clock := clockOr(d.clock)
+	deadline := clock.Now().Add(d.leakGrace)
+	var leaked []string
+	for {
+		leaked = leaked[:0]
+		for id, stack := range goroutineStacks() {
+			if !d.goroutines[id] && !leakIgnoredStack(stack) {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || !clock.Now().Before(deadline) {
+			break
+		}
+		<-clock.After(leakPoll)
+	}
+	if len(leaked) == 0 {
+		return
+	}
+
+	for _, stack := range leaked {
+		msgGoroutineLeaked.emit(
+			log.String("stack", stack),
+		)
+	}
+	d.reportMu.Lock()
+	d.report.Leaked = leaked
+	d.reportMu.Unlock()
+}
+
+// goroutineStacks returns the stacks of the running goroutines, other than the
+// calling one, by goroutine ID.
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[string]string)
+	for i, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+		header, _, _ := strings.Cut(string(stack), " [")
+		stacks[strings.TrimPrefix(header, "goroutine ")] = string(stack)
+	}
+	return stacks
+}
+
+// leakIgnoredStack reports whether the goroutine running stack outlives Serve
+// by design.
+func leakIgnoredStack(stack string) bool {
+	for _, fn := range leakIgnored {
+		if strings.Contains(stack, fn) {
+			return true
+		}
+	}
+	return false
+}
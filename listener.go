@@ -0,0 +1,365 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/uber-go/zap"
+)
+
+const (
+	// envListeners names the listeners handed to a process by its parent
+	// during an upgrade, in file descriptor order from 3.
+	envListeners = "DISSEMBLER_LISTENERS"
+	// listenFDStart is the first file descriptor handed down by the parent.
+	listenFDStart = 3
+)
+
+// listeners is the registry of listeners and packet connections opened
+// through Listen and ListenPacket.
+var listeners = newRegistry()
+
+// Listen announces on the local network address like net.Listen. If the
+// process was started by an upgrade and its parent handed down a listener for
+// the same network and address, that listener is reused instead, so no
+// connection is refused while the new process starts.
+//
+// Listeners opened through Listen are handed to the new process when the
+// process is upgraded with SIGUSR2. The socket is tuned by opts.
+func Listen(network, address string, opts ...ListenOption) (net.Listener, error) {
+	return listeners.listen(network, address, newListenConfig(opts))
+}
+
+// ListenPacket announces on the local network address like net.ListenPacket,
+// for packet-oriented networks such as "udp" and "unixgram". Like Listen, it
+// reuses a packet connection handed down by the parent during an upgrade and
+// hands its own connections to the new process on the next upgrade.
+//
+// Closing a "unixgram" connection removes its socket file, unless the
+// connection has been handed to a new process. The socket is tuned by opts;
+// options for stream sockets, such as KeepAlive, are ignored.
+func ListenPacket(network, address string, opts ...ListenOption) (net.PacketConn, error) {
+	return listeners.listenPacket(network, address, newListenConfig(opts))
+}
+
+// ListenAddr is a local network address to listen on.
+type ListenAddr struct {
+	// Network is the stream network, such as "tcp", "tcp6" or "unix". If
+	// empty, "tcp" is used.
+	Network string
+	// Address is the local address to listen on.
+	Address string
+	// TLS serves TLS on the address, where the server supports it.
+	TLS bool
+	// Options tune the socket.
+	Options []ListenOption
+}
+
+// network returns the network of a, defaulting to "tcp".
+func (a ListenAddr) network() string {
+	if a.Network == "" {
+		return "tcp"
+	}
+	return a.Network
+}
+
+// String returns the network and address, such as "tcp6 [::1]:443".
+func (a ListenAddr) String() string {
+	return a.network() + " " + a.Address
+}
+
+// ListenAll announces on every address through Listen, for components serving
+// several sockets, such as HTTP and HTTPS, IPv4 and IPv6, or TCP and a Unix
+// socket. Each listener is handed to the new process on upgrade like any
+// other. If any address cannot be bound, the listeners already opened are
+// closed and the error is returned.
+func ListenAll(addrs ...ListenAddr) ([]net.Listener, error) {
+	lns := make([]net.Listener, 0, len(addrs))
+	for _, a := range addrs {
+		ln, err := Listen(a.network(), a.Address, a.Options...)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("listen %s: %w", a, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// filer is implemented by listeners and connections backed by a file
+// descriptor.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// managed is implemented by the listeners and connections in the registry.
+type managed interface {
+	filer
+	config() *listenConfig
+}
+
+// registry tracks inherited and active listeners by network and address.
+type registry struct {
+	mu        sync.Mutex
+	inherited map[string]*os.File
+	meta      map[string]listenerMeta
+	active    map[string]managed
+	handedOff bool
+}
+
+// newRegistry returns a registry holding the listeners handed down by the
+// parent process, if any.
+func newRegistry() *registry {
+	r := &registry{
+		inherited: make(map[string]*os.File),
+		meta:      make(map[string]listenerMeta),
+		active:    make(map[string]managed),
+	}
+
+	names, metas := os.Getenv(envListeners), os.Getenv(envListenerMeta)
+	if names == "" {
+		return r
+	}
+	os.Unsetenv(envListeners)
+	os.Unsetenv(envListenerMeta)
+	keys := strings.Split(names, ";")
+	for i, key := range keys {
+		fd := uintptr(listenFDStart + i)
+		r.inherited[key] = os.NewFile(fd, key)
+	}
+	// A parent predating listener descriptions hands none down, and its
+	// listeners are reused unverified.
+	if metas != "" {
+		for i, m := range strings.Split(metas, ";") {
+			if i < len(keys) {
+				r.meta[keys[i]] = decodeListenerMeta(m)
+			}
+		}
+	}
+	return r
+}
+
+// claim removes and returns the inherited socket for k, if any, once verified
+// to be configured compatibly with c.
+func (r *registry) claim(k string, c *listenConfig) (*os.File, error) {
+	f, ok := r.inherited[k]
+	if !ok {
+		return nil, nil
+	}
+	if parent, ok := r.meta[k]; ok {
+		if err := c.meta().verify(k, parent); err != nil {
+			return nil, err
+		}
+	}
+	delete(r.inherited, k)
+	return f, nil
+}
+
+// key identifies a listener by network and address.
+func key(network, address string) string {
+	return network + "|" + address
+}
+
+// listen returns the inherited stream listener for network and address, or a
+// new one.
+func (r *registry) listen(network, address string, c *listenConfig) (net.Listener, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key(network, address)
+	f, err := r.claim(k, c)
+	if err != nil {
+		return nil, err
+	}
+	var ln net.Listener
+	if f != nil {
+		ln, err = net.FileListener(f)
+		f.Close()
+	} else {
+		ln, err = c.netConfig().Listen(context.Background(), network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{Listener: ln, key: k, cfg: c}
+	r.active[k] = l
+	return l, nil
+}
+
+// listenPacket returns the inherited packet connection for network and
+// address, or a new one.
+func (r *registry) listenPacket(network, address string, lc *listenConfig) (net.PacketConn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key(network, address)
+	f, err := r.claim(k, lc)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.PacketConn
+	if f != nil {
+		conn, err = net.FilePacketConn(f)
+		f.Close()
+	} else {
+		conn, err = lc.netConfig().ListenPacket(context.Background(), network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &packetConn{PacketConn: conn, key: k, network: network, address: address, cfg: lc}
+	r.active[k] = c
+	return c, nil
+}
+
+// release removes a closed listener or connection from the registry. It
+// reports whether the registry has handed its listeners to a new process.
+func (r *registry) release(k string, f managed) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active[k] == f {
+		delete(r.active, k)
+	}
+	return r.handedOff
+}
+
+// closeInherited closes inherited listeners that were not claimed, so their
+// sockets are not held open by a process that will never serve them.
+func (r *registry) closeInherited() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, f := range r.inherited {
+		msgClosingInheritedListener.emit(
+			log.String("listener", k))
+		f.Close()
+		delete(r.inherited, k)
+	}
+}
+
+// files duplicates the file descriptors of every active listener, returning
+// them with the values of envListeners and envListenerMeta describing them.
+func (r *registry) files() (files []*os.File, names, metas string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]string, 0, len(r.active))
+	for k := range r.active {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	files = make([]*os.File, 0, len(keys))
+	meta := make([]string, 0, len(keys))
+	for _, k := range keys {
+		f, err := r.active[k].File()
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, "", "", fmt.Errorf("listener %s: %w", k, err)
+		}
+		files = append(files, f)
+		meta = append(meta, r.active[k].config().meta().encode())
+	}
+	return files, strings.Join(keys, ";"), strings.Join(meta, ";"), nil
+}
+
+// handOff records that the listeners now belong to a new process as well, so
+// closing them must leave their socket files in place.
+func (r *registry) handOff() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handedOff = true
+	for _, f := range r.active {
+		if l, ok := f.(*listener); ok {
+			if ul, ok := l.Listener.(*net.UnixListener); ok {
+				ul.SetUnlinkOnClose(false)
+			}
+		}
+	}
+}
+
+// listener is a stream listener tracked by the registry.
+type listener struct {
+	net.Listener
+	key string
+	cfg *listenConfig
+}
+
+// config returns the configuration of the listener.
+func (l *listener) config() *listenConfig {
+	return l.cfg
+}
+
+// Accept waits for and returns the next connection, tuned as configured.
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.cfg.tuneConn(conn), nil
+}
+
+// File returns a duplicate of the listener's file descriptor.
+func (l *listener) File() (*os.File, error) {
+	f, ok := l.Listener.(filer)
+	if !ok {
+		return nil, fmt.Errorf("%T has no file descriptor", l.Listener)
+	}
+	return f.File()
+}
+
+// Close closes the listener and removes it from the registry.
+func (l *listener) Close() error {
+	listeners.release(l.key, l)
+	return l.Listener.Close()
+}
+
+// packetConn is a packet connection tracked by the registry.
+type packetConn struct {
+	net.PacketConn
+	key     string
+	network string
+	address string
+	cfg     *listenConfig
+}
+
+// config returns the configuration of the connection.
+func (c *packetConn) config() *listenConfig {
+	return c.cfg
+}
+
+// File returns a duplicate of the connection's file descriptor.
+func (c *packetConn) File() (*os.File, error) {
+	f, ok := c.PacketConn.(filer)
+	if !ok {
+		return nil, fmt.Errorf("%T has no file descriptor", c.PacketConn)
+	}
+	return f.File()
+}
+
+// Close closes the connection, removes it from the registry and, for unixgram
+// sockets not handed to a new process, removes the socket file.
+func (c *packetConn) Close() error {
+	handedOff := listeners.release(c.key, c)
+	err := c.PacketConn.Close()
+	if c.network == "unixgram" && !handedOff {
+		os.Remove(c.address)
+	}
+	return err
+}
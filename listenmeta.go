@@ -0,0 +1,124 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// envListenerMeta describes the listeners named by envListeners, in the same
+// order, so the new process can verify it configures them compatibly.
+const envListenerMeta = "DISSEMBLER_LISTENER_META"
+
+// ErrListenerChanged is returned by Listen and ListenPacket in a process
+// started by an upgrade when the listener handed down by the parent was
+// configured incompatibly with the one requested, such as with another name,
+// other socket options, or with TLS or the PROXY protocol turned on or off.
+// Both processes accept on an inherited socket until the parent stops, so
+// reusing it would serve clients inconsistently; the upgrade fails instead and
+// the parent keeps serving. A change only to TLS settings, such as the client
+// certificate policy, is logged and allowed.
+var ErrListenerChanged = errors.New("inherited listener configured incompatibly")
+
+// Named names the listener, so it is told apart in logs and a process started
+// by an upgrade refuses to reuse it for a listener of another name.
+func Named(name string) ListenOption {
+	return func(c *listenConfig) {
+		c.name = name
+	}
+}
+
+// listenerMeta describes how a listener is configured.
+type listenerMeta struct {
+	name     string
+	sockopts string
+	proxy    bool
+	tls      string
+}
+
+// meta describes the listener configured by c.
+func (c *listenConfig) meta() listenerMeta {
+	m := listenerMeta{name: c.name, proxy: c.proxyTimeout > 0}
+	var opts []string
+	if c.dualStack != nil {
+		opts = append(opts, fmt.Sprintf("dualstack=%t", *c.dualStack))
+	}
+	if c.reusePort {
+		opts = append(opts, "reuseport")
+	}
+	if c.deferAccept > 0 {
+		opts = append(opts, "deferaccept="+c.deferAccept.String())
+	}
+	m.sockopts = strings.Join(opts, ",")
+	if c.tls != nil {
+		m.tls = tlsHash(c.tls)
+	}
+	return m
+}
+
+// tlsHash returns a short hash of the settings of config, leaving out its
+// certificates so they may be renewed.
+func tlsHash(config *tls.Config) string {
+	h := sha256.New()
+	fmt.Fprintln(h, config.MinVersion, config.MaxVersion, config.ClientAuth,
+		config.CipherSuites, config.CurvePreferences, config.NextProtos)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// encode returns m as a string free of the separator of envListenerMeta.
+func (m listenerMeta) encode() string {
+	v := url.Values{}
+	v.Set("name", m.name)
+	v.Set("sockopts", m.sockopts)
+	if m.proxy {
+		v.Set("proxy", "1")
+	}
+	v.Set("tls", m.tls)
+	return v.Encode()
+}
+
+// decodeListenerMeta parses a listener description made by encode.
+func decodeListenerMeta(s string) listenerMeta {
+	v, _ := url.ParseQuery(s)
+	return listenerMeta{
+		name:     v.Get("name"),
+		sockopts: v.Get("sockopts"),
+		proxy:    v.Get("proxy") == "1",
+		tls:      v.Get("tls"),
+	}
+}
+
+// verify checks that the listener k described by m may reuse the socket
+// handed down by a parent describing it as parent, logging changes that are
+// allowed.
+func (m listenerMeta) verify(k string, parent listenerMeta) error {
+	var changed string
+	switch {
+	case m.name != parent.name:
+		changed = fmt.Sprintf("name %q, was %q", m.name, parent.name)
+	case m.sockopts != parent.sockopts:
+		changed = fmt.Sprintf("socket options %q, were %q", m.sockopts, parent.sockopts)
+	case m.proxy != parent.proxy:
+		changed = fmt.Sprintf("PROXY protocol %t, was %t", m.proxy, parent.proxy)
+	case (m.tls == "") != (parent.tls == ""):
+		changed = fmt.Sprintf("TLS %t, was %t", m.tls != "", parent.tls != "")
+	case m.tls != parent.tls:
+		msgListenerTLSChanged.emit(
+			log.String("listener", k),
+			log.String("name", m.name),
+		)
+	}
+	if changed != "" {
+		return fmt.Errorf("listener %s: %w: %s", k, ErrListenerChanged, changed)
+	}
+	return nil
+}
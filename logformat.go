@@ -0,0 +1,37 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// formattedSecond is a timestamp formatted to the second.
+type formattedSecond struct {
+	unix int64
+	loc  *time.Location
+	text string
+}
+
+// rfc3339Formatter is log.RFC3339Formatter, except that timestamps are in the
+// zone pinned by WithTimezone, and that the formatted timestamp is reused for
+// every entry logged within the same second. Zap's encoders are pooled and its
+// fields are values, so formatting the timestamp is the only allocation made
+// per entry; caching it makes logging on hot paths, such as signal handling
+// and drain reporting, allocation free.
+func rfc3339Formatter(key string) log.TimeFormatter {
+	var cache atomic.Value // *formattedSecond
+	return log.TimeFormatter(func(t time.Time) log.Field {
+		unix, loc := t.Unix(), Location()
+		if c, ok := cache.Load().(*formattedSecond); ok && c.unix == unix && c.loc == loc {
+			return log.String(key, c.text)
+		}
+		text := t.In(loc).Format(time.RFC3339)
+		cache.Store(&formattedSecond{unix: unix, loc: loc, text: text})
+		return log.String(key, text)
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	log "github.com/uber-go/zap"
+)
+
+// DissemblerLogger is the logger Dissembler logs to.
+//
+// Deprecated: Use SetLogger and Logger, which are safe for concurrent use. A
+// logger assigned to DissemblerLogger before Serve is adopted as if passed to
+// SetLogger when Serve begins; later assignments are ignored.
+var DissemblerLogger log.Logger
+
+// adoptedLogger is the value of DissemblerLogger last adopted.
+var adoptedLogger log.Logger
+
+// adoptDissemblerLogger adopts a logger assigned to DissemblerLogger since it
+// was last adopted. Loggers of types that cannot be compared are adopted
+// every time.
+func adoptDissemblerLogger() {
+	l := DissemblerLogger
+	if l == nil || reflect.TypeOf(l).Comparable() && l == adoptedLogger {
+		return
+	}
+	SetLogger(l)
+	adoptedLogger = l
+}
+
+// logger holds the logger Dissembler logs to, as a *loggerHolder, so it may
+// be replaced while other goroutines, such as Wait, are logging.
+var logger atomic.Value
+
+// loggerHolder wraps the logger, as atomic.Value requires every value stored
+// to have the same concrete type.
+type loggerHolder struct {
+	log.Logger
+}
+
+// Logger returns the logger Dissembler logs to. By default it writes JSON to
+// standard output.
+func Logger() log.Logger {
+	return logger.Load().(*loggerHolder).Logger
+}
+
+// SetLogger replaces the logger Dissembler logs to. It is safe to call at any
+// time, including while a Dissembler is serving; entries being logged
+// concurrently go to either the old or the new logger.
+func SetLogger(l log.Logger) {
+	logger.Store(&loggerHolder{l})
+}
@@ -0,0 +1,58 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"syscall"
+
+	log "github.com/uber-go/zap"
+)
+
+// WithShutdownInhibitor takes a systemd-logind shutdown inhibitor lock in
+// delay mode while the lifecycle runs, so a host reboot or power-off waits
+// for the lifecycle to stop, up to logind's InhibitDelayMaxSec, instead of
+// killing it mid-drain. When logind announces the shutdown, the lifecycle is
+// stopped as if sent SIGTERM, and the lock is released once Stop completes.
+//
+// Inhibitors are only supported on Linux with systemd-logind; elsewhere, or
+// if the system bus is unavailable, a warning is logged and Serve proceeds
+// without one.
+func WithShutdownInhibitor() Option {
+	return func(d *Dissembler) {
+		d.inhibit = true
+	}
+}
+
+// inhibitShutdown takes the shutdown inhibitor if enabled by
+// WithShutdownInhibitor.
+func (d *Dissembler) inhibitShutdown() {
+	if !d.inhibit {
+		return
+	}
+	inhibitor, err := takeInhibitor(func() {
+		msgHostShuttingDown.emit()
+		go d.request(context.Background(), syscall.SIGTERM)
+	})
+	if err != nil {
+		msgUnableToTakeInhibitor.emit(
+			log.String("error", err.Error()),
+		)
+		return
+	}
+	d.inhibitor = inhibitor
+}
+
+// releaseInhibitor releases the shutdown inhibitor, if taken, letting the
+// host shut down.
+func (d *Dissembler) releaseInhibitor() {
+	if d.inhibitor == nil {
+		return
+	}
+	if err := d.inhibitor.Close(); err != nil {
+		msgUnableToReleaseInhibitor.emit(
+			log.String("error", err.Error()),
+		)
+	}
+}
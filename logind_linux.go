@@ -0,0 +1,151 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+)
+
+const (
+	logindService = "org.freedesktop.login1"
+	logindPath    = "/org/freedesktop/login1"
+	logindManager = "org.freedesktop.login1.Manager"
+)
+
+// logindInhibitor is a logind inhibitor lock, held as long as its file
+// descriptor is open.
+type logindInhibitor struct {
+	bus  *dbusConn
+	fd   int
+	once sync.Once
+}
+
+// takeInhibitor takes a delay-mode shutdown inhibitor from logind and calls
+// onShutdown when logind announces the host is shutting down.
+func takeInhibitor(onShutdown func()) (io.Closer, error) {
+	bus, err := dialLogind("PrepareForShutdown")
+	if err != nil {
+		return nil, err
+	}
+	fd, err := inhibit(bus, "shutdown", "Draining before shutdown")
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+
+	go watchLogind(bus, "PrepareForShutdown", func(active bool) {
+		if active {
+			onShutdown()
+		}
+	})
+	return &logindInhibitor{bus: bus, fd: fd}, nil
+}
+
+// Close releases the lock.
+func (i *logindInhibitor) Close() error {
+	var err error
+	i.once.Do(func() {
+		err = syscall.Close(i.fd)
+		// Closing the socket ends watchLogind, which owns the connection.
+		i.bus.conn.Close()
+	})
+	return err
+}
+
+// watchSleep calls suspend when logind announces the host is going to sleep
+// and resume when it has woken, until quit is closed. A delay-mode sleep
+// inhibitor is held while awake, so suspend runs before the host sleeps; it
+// is released once suspend returns and taken again on resume.
+func watchSleep(quit <-chan struct{}, suspend, resume func()) error {
+	bus, err := dialLogind("PrepareForSleep")
+	if err != nil {
+		return err
+	}
+	fd, err := inhibit(bus, "sleep", "Pausing work before sleep")
+	if err != nil {
+		bus.Close()
+		return err
+	}
+
+	go func() {
+		<-quit
+		bus.conn.Close()
+	}()
+	go func() {
+		defer func() {
+			if fd >= 0 {
+				syscall.Close(fd)
+			}
+		}()
+		watchLogind(bus, "PrepareForSleep", func(sleeping bool) {
+			if sleeping {
+				suspend()
+				if fd >= 0 {
+					syscall.Close(fd)
+					fd = -1
+				}
+				return
+			}
+			if fd < 0 {
+				// Without the lock the next suspend is not delayed, but is
+				// still announced.
+				fd, _ = inhibit(bus, "sleep", "Pausing work before sleep")
+			}
+			resume()
+		})
+	}()
+	return nil
+}
+
+// dialLogind connects to the system bus and subscribes to the logind Manager
+// signal member. Subscribing precedes inhibiting, so a signal sent meanwhile
+// is not missed.
+func dialLogind(member string) (*dbusConn, error) {
+	bus, err := dialSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := bus.call("org.freedesktop.DBus", "/org/freedesktop/DBus",
+		"org.freedesktop.DBus", "AddMatch",
+		"type='signal',sender='"+logindService+"',interface='"+logindManager+"',member='"+member+"'"); err != nil {
+		bus.Close()
+		return nil, err
+	}
+	return bus, nil
+}
+
+// inhibit takes a delay-mode inhibitor lock on what, returning its file
+// descriptor.
+func inhibit(bus *dbusConn, what, why string) (int, error) {
+	reply, err := bus.call(logindService, logindPath, logindManager, "Inhibit",
+		what, "dissembler", why, "delay")
+	if err != nil {
+		return -1, err
+	}
+	if len(reply.fds) != 1 {
+		closeFDs(reply.fds)
+		return -1, errors.New("logind returned no inhibitor lock")
+	}
+	return reply.fds[0], nil
+}
+
+// watchLogind calls fn with the boolean argument of each member signal
+// received, until bus is closed.
+func watchLogind(bus *dbusConn, member string, fn func(bool)) {
+	for {
+		m, err := bus.read()
+		if err != nil {
+			closeFDs(bus.fds)
+			return
+		}
+		closeFDs(m.fds)
+		if m.typ == dbusSignal && m.member == member &&
+			m.signature == "b" && len(m.body) == 4 {
+			fn(m.body[0]|m.body[3] != 0)
+		}
+	}
+}
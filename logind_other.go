@@ -0,0 +1,22 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import (
+	"errors"
+	"io"
+)
+
+// takeInhibitor is not supported outside Linux.
+func takeInhibitor(onShutdown func()) (io.Closer, error) {
+	return nil, errors.New("shutdown inhibitors require systemd-logind")
+}
+
+// watchSleep is not supported outside Linux.
+func watchSleep(quit <-chan struct{}, suspend, resume func()) error {
+	return errors.New("suspend and resume events require systemd-logind")
+}
@@ -0,0 +1,178 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// longLivedPoll is how often idleness is polled under CloseAfterIdle.
+const longLivedPoll = 100 * time.Millisecond
+
+// LongLivedStrategy determines how long-lived connections, such as WebSockets
+// or streaming RPCs, are ended while an adapter drains.
+type LongLivedStrategy int
+
+const (
+	// CloseImmediately closes long-lived connections as soon as draining
+	// begins.
+	CloseImmediately LongLivedStrategy = iota
+	// CloseAfterIdle closes each long-lived connection once it is idle, or
+	// when the grace period ends.
+	CloseAfterIdle
+	// NotifyThenClose notifies each long-lived connection's peer that the
+	// server is going away and closes the connection when the grace period
+	// ends, unless the peer disconnects first.
+	NotifyThenClose
+)
+
+// String returns the name of the strategy.
+func (s LongLivedStrategy) String() string {
+	switch s {
+	case CloseImmediately:
+		return "close-immediately"
+	case CloseAfterIdle:
+		return "close-after-idle"
+	case NotifyThenClose:
+		return "notify-then-close"
+	}
+	return fmt.Sprintf("LongLivedStrategy(%d)", int(s))
+}
+
+// LongLivedPolicy configures how an adapter ends long-lived connections while
+// draining.
+type LongLivedPolicy struct {
+	Strategy LongLivedStrategy
+	// Grace bounds how long connections are given under CloseAfterIdle and
+	// NotifyThenClose before they are closed.
+	Grace time.Duration
+}
+
+// LongLivedConnHandler is the hook adapters call to end a long-lived
+// connection while draining. Handlers serving WebSockets or streams register
+// an implementation for each connection with the adapter, since graceful
+// server shutdown does not reach connections that have left the request cycle.
+type LongLivedConnHandler interface {
+	// Notify tells the peer the server is going away, for example by sending
+	// a WebSocket close frame. It is called under NotifyThenClose.
+	Notify() error
+	// Idle reports whether no message is in progress on the connection. It is
+	// polled under CloseAfterIdle.
+	Idle() bool
+	// Close closes the connection.
+	Close() error
+}
+
+// longLivedConns tracks the long-lived connections of an adapter.
+type longLivedConns struct {
+	mu    sync.Mutex
+	conns map[*longLivedConn]struct{}
+}
+
+// longLivedConn is a tracked connection. Connections are keyed by their
+// registration rather than their handler, which need not be comparable.
+type longLivedConn struct {
+	handler LongLivedConnHandler
+	done    chan struct{}
+}
+
+// track registers a connection and returns the function that deregisters it
+// once it has ended.
+func (l *longLivedConns) track(c LongLivedConnHandler) func() {
+	conn := &longLivedConn{handler: c, done: make(chan struct{})}
+	l.mu.Lock()
+	if l.conns == nil {
+		l.conns = make(map[*longLivedConn]struct{})
+	}
+	l.conns[conn] = struct{}{}
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.conns, conn)
+			l.mu.Unlock()
+			close(conn.done)
+		})
+	}
+}
+
+// len returns the number of tracked connections.
+func (l *longLivedConns) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+// drain ends every tracked connection according to policy, returning once
+// they have all been closed or ctx is done.
+func (l *longLivedConns) drain(ctx context.Context, clock Clock, policy LongLivedPolicy) {
+	l.mu.Lock()
+	conns := make([]*longLivedConn, 0, len(l.conns))
+	for conn := range l.conns {
+		conns = append(conns, conn)
+	}
+	l.mu.Unlock()
+
+	if len(conns) > 0 {
+		msgDrainingLongLived.emit(
+			log.Int("connections", len(conns)),
+			log.String("strategy", policy.Strategy.String()),
+		)
+	}
+
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *longLivedConn) {
+			defer wg.Done()
+			endLongLived(ctx, clock, conn.handler, conn.done, policy)
+		}(conn)
+	}
+	wg.Wait()
+}
+
+// endLongLived ends a single connection according to policy.
+func endLongLived(ctx context.Context, clock Clock, c LongLivedConnHandler, done <-chan struct{}, policy LongLivedPolicy) {
+	defer c.Close()
+
+	grace := clock.NewTimer(policy.Grace)
+	defer grace.Stop()
+
+	switch policy.Strategy {
+	case CloseAfterIdle:
+		ticker := clock.NewTicker(longLivedPoll)
+		defer ticker.Stop()
+		for !c.Idle() {
+			select {
+			case <-ticker.C():
+			case <-done:
+				return
+			case <-grace.C():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+
+	case NotifyThenClose:
+		if err := c.Notify(); err != nil {
+			msgUnableToNotifyLongLived.emit(
+				log.String("error", err.Error()),
+			)
+			return
+		}
+		select {
+		case <-done:
+		case <-grace.C():
+		case <-ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"math"
+	"os"
+	"runtime"
+
+	log "github.com/uber-go/zap"
+)
+
+// WithAutoMaxProcs sets GOMAXPROCS from the CPU quota of the process's
+// cgroup, as container runtimes such as Kubernetes impose, so the runtime
+// does not schedule more threads than the quota allows and get throttled.
+// The quota is rounded down, to no less than one CPU, and bounded by any
+// CPU affinity set with WithCPUAffinity. It is evaluated before Init and
+// again on every reload, and each decision is logged. GOMAXPROCS is left
+// alone if the GOMAXPROCS environment variable is set, and if no quota applies
+// it is only bounded by the CPU affinity.
+func WithAutoMaxProcs() Option {
+	return func(d *Dissembler) {
+		d.autoMaxProcs = true
+	}
+}
+
+// adjustMaxProcs sets GOMAXPROCS from the cgroup CPU quota if enabled by
+// WithAutoMaxProcs.
+func (d *Dissembler) adjustMaxProcs() {
+	if !d.autoMaxProcs {
+		return
+	}
+	if env := os.Getenv("GOMAXPROCS"); env != "" {
+		msgMaxProcsFromEnv.emit(
+			log.String("gomaxprocs", env),
+		)
+		return
+	}
+
+	quota, ok, err := cpuQuota()
+	if err != nil {
+		msgUnableToReadCPUQuota.emit(
+			log.String("error", err.Error()),
+		)
+		return
+	}
+
+	if !ok {
+		// Without a quota the runtime default stands, bounded only by any
+		// CPU affinity.
+		procs := runtime.GOMAXPROCS(0)
+		if len(d.cpus) > 0 && len(d.cpus) < procs {
+			procs = len(d.cpus)
+			runtime.GOMAXPROCS(procs)
+		}
+		msgMaxProcsWithoutQuota.emit(
+			log.Int("gomaxprocs", procs),
+		)
+		return
+	}
+
+	procs := int(math.Floor(quota))
+	if procs < 1 {
+		procs = 1
+	}
+	if len(d.cpus) > 0 && len(d.cpus) < procs {
+		procs = len(d.cpus)
+	}
+
+	previous := runtime.GOMAXPROCS(procs)
+	msgMaxProcsFromQuota.emit(
+		log.Float64("quota", quota),
+		log.Int("previous", previous),
+		log.Int("gomaxprocs", procs),
+	)
+}
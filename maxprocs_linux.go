@@ -0,0 +1,172 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cpuQuota returns the CPU quota of the process's cgroup in CPUs, reporting
+// false if no quota applies. Both cgroup v2 (cpu.max) and v1
+// (cpu.cfs_quota_us and cpu.cfs_period_us) are supported.
+func cpuQuota() (float64, bool, error) {
+	dir, v2, err := cgroupCPUDir()
+	if err != nil || dir == "" {
+		return 0, false, err
+	}
+
+	if v2 {
+		b, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 {
+			return 0, false, fmt.Errorf("malformed cpu.max %q", b)
+		}
+		if fields[0] == "max" {
+			return 0, false, nil
+		}
+		return quotaRatio(fields[0], fields[1])
+	}
+
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false, err
+	}
+	if strings.TrimSpace(string(quota)) == "-1" {
+		return 0, false, nil
+	}
+	return quotaRatio(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// quotaRatio returns quota divided by period.
+func quotaRatio(quota, period string) (float64, bool, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	if q <= 0 || p <= 0 {
+		return 0, false, nil
+	}
+	return q / p, true, nil
+}
+
+// cgroupCPUDir returns the directory holding the CPU controller files of the
+// process's cgroup, and whether it is a cgroup v2 hierarchy. It returns an
+// empty directory if the process is in no CPU cgroup.
+func cgroupCPUDir() (string, bool, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	// Lines are hierarchy-ID:controllers:path; the v2 hierarchy has ID 0 and
+	// no controllers.
+	var v1Path, v2Path string
+	v2 := false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path, v2 = parts[2], true
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == "cpu" {
+				v1Path = parts[2]
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", false, err
+	}
+
+	if v1Path != "" {
+		dir, err := cgroupMount("cgroup", "cpu", v1Path)
+		return dir, false, err
+	}
+	if v2 {
+		dir, err := cgroupMount("cgroup2", "", v2Path)
+		return dir, true, err
+	}
+	return "", false, nil
+}
+
+// cgroupMount returns the directory of the cgroup at path within the mounted
+// hierarchy of type fstype, which for v1 must carry controller.
+func cgroupMount(fstype, controller, path string) (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Lines are: ID parent major:minor root mount-point options [optional...]
+	// - fstype source super-options.
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+3 > len(fields) || fields[sep+1] != fstype {
+			continue
+		}
+		if controller != "" && !hasOption(fields[sep+3], controller) {
+			continue
+		}
+
+		root, mount := fields[3], fields[4]
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			// The cgroup lies outside the mounted subtree, as inside a
+			// container with its own cgroup namespace; the mount is the
+			// process's cgroup.
+			rel = "."
+		}
+		return filepath.Join(mount, rel), nil
+	}
+	return "", s.Err()
+}
+
+// hasOption reports whether the comma-separated options include opt.
+func hasOption(options, opt string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
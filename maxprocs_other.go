@@ -0,0 +1,12 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+// cpuQuota reports that no CPU quota applies; cgroups exist only on Linux.
+func cpuQuota() (float64, bool, error) {
+	return 0, false, nil
+}
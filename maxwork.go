@@ -0,0 +1,93 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// workPoll is how often the work done is checked against WithMaxWork.
+const workPoll = time.Second
+
+// WorkCounter is an optional interface that may be implemented by a Lifecycle
+// to report how many units of work, such as requests or jobs, it has
+// completed. It backs WithMaxWork.
+type WorkCounter interface {
+	WorkDone() int64
+}
+
+// WithMaxWork stops the lifecycle gracefully, as SIGTERM does, once it has
+// completed n units of work as reported by WorkCounter, so a service manager
+// or orchestrator restarts it afresh. Recycling worker processes this way
+// bounds the effect of slow leaks and heap fragmentation. To keep a fleet from
+// restarting in step, each process picks its limit at random between n and
+// n+jitter. Serve then returns with ExitMaxWork.
+func WithMaxWork(n, jitter int64) Option {
+	return func(d *Dissembler) {
+		if jitter > 0 {
+			n += rand.Int63n(jitter + 1)
+		}
+		d.maxWork = n
+	}
+}
+
+// watchWork stops the lifecycle once it has done the work set by WithMaxWork.
+func (d *Dissembler) watchWork() {
+	if d.maxWork <= 0 {
+		return
+	}
+	wc, ok := d.lifecycle.(WorkCounter)
+	if !ok {
+		msgUnableToCountWork.emit(
+			log.String("error", "lifecycle does not implement WorkCounter"),
+		)
+		return
+	}
+
+	go func() {
+		t := clockOr(d.clock).NewTicker(workPoll)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C():
+			case <-d.quit:
+				return
+			}
+			if done := wc.WorkDone(); done >= d.maxWork {
+				msgMaxWorkReached.emit(
+					log.Int64("work_done", done),
+					log.Int64("max_work", d.maxWork),
+				)
+				atomic.StoreInt32(&d.maxWorkReached, 1)
+				d.request(context.Background(), syscall.SIGTERM)
+				return
+			}
+		}
+	}()
+}
+
+// WorkDone returns the work done by every initialized component implementing
+// WorkCounter.
+func (g *Group) WorkDone() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var done int64
+	for _, c := range g.components {
+		if wc, ok := c.lc.(WorkCounter); ok && c.initialized {
+			done += wc.WorkDone()
+		}
+	}
+	return done
+}
+
+// WorkDone returns the number of requests served.
+func (h *HTTPServer) WorkDone() int64 {
+	return atomic.LoadInt64(&h.served)
+}
@@ -0,0 +1,181 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"sort"
+
+	log "github.com/uber-go/zap"
+)
+
+// MessageID identifies an operator-facing log message. Every entry Dissembler
+// logs carries its message's ID in the "message_id" field, so alerts and
+// dashboards can match on the ID rather than on wording, which may change.
+// IDs are never reused or renumbered. Events are identified by their
+// EventType.
+type MessageID string
+
+// Message is an entry of the message catalog.
+type Message struct {
+	// ID is the message's stable identifier, such as "DSMB-0001".
+	ID MessageID
+	// Level is the level the message is logged at.
+	Level log.Level
+	// Text is the message as logged.
+	Text string
+}
+
+// catalog holds every message, in the order declared.
+var catalog []Message
+
+// message adds a message to the catalog.
+func message(id MessageID, level log.Level, text string) Message {
+	m := Message{ID: id, Level: level, Text: text}
+	catalog = append(catalog, m)
+	return m
+}
+
+// Messages returns the message catalog, ordered by ID, for documentation
+// tooling.
+func Messages() []Message {
+	messages := append([]Message(nil), catalog...)
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	return messages
+}
+
+// emit logs the message with fields, its ID and the run ID. The fields are
+// copied into a slice of their own, which the logger may retain, so the
+// caller's fields need not escape.
+func (m Message) emit(fields ...log.Field) {
+	all := make([]log.Field, 0, len(fields)+2)
+	all = append(append(all, fields...),
+		log.String("message_id", string(m.ID)),
+		log.String("run_id", RunID()),
+	)
+	Logger().Log(m.Level, m.Text, all...)
+}
+
+// The message catalog. New messages take the next free ID.
+var (
+	msgWaitFailed                  = message("DSMB-0001", log.ErrorLevel, "Unable to finish waiting for Dissembler to shutdown")
+	msgLifecycleFailedToStart      = message("DSMB-0002", log.ErrorLevel, "lifecycle failed to start")
+	msgSignalCaught                = message("DSMB-0003", log.InfoLevel, "signal caught")
+	msgUnableToUpgrade             = message("DSMB-0004", log.ErrorLevel, "Unable to upgrade")
+	msgSignalIgnored               = message("DSMB-0005", log.WarnLevel, "signal ignored")
+	msgUnableToStopLifecycle       = message("DSMB-0006", log.ErrorLevel, "Unable to stop lifecycle")
+	msgUnableToReloadGC            = message("DSMB-0007", log.ErrorLevel, "Unable to reload GC configuration")
+	msgUnableToReloadFlags         = message("DSMB-0008", log.ErrorLevel, "Unable to reload flags")
+	msgReloadUnsupported           = message("DSMB-0009", log.WarnLevel, "Unable to reload lifecycle")
+	msgReloadFailed                = message("DSMB-0010", log.ErrorLevel, "Unable to reload lifecycle")
+	msgReloaded                    = message("DSMB-0011", log.InfoLevel, "reloaded")
+	msgUnableToServeAdminAPI       = message("DSMB-0012", log.ErrorLevel, "Unable to serve admin API")
+	msgAdminReloadRequested        = message("DSMB-0013", log.InfoLevel, "admin reload requested")
+	msgNicenessSet                 = message("DSMB-0014", log.InfoLevel, "niceness set")
+	msgCPUAffinityPinned           = message("DSMB-0015", log.InfoLevel, "CPU affinity pinned")
+	msgUnableToAwaitProcess        = message("DSMB-0016", log.WarnLevel, "Unable to await process stopping first")
+	msgAwaitedProcess              = message("DSMB-0017", log.InfoLevel, "awaited process stopping first")
+	msgBootStepComplete            = message("DSMB-0018", log.DebugLevel, "boot step complete")
+	msgBootComplete                = message("DSMB-0019", log.InfoLevel, "boot complete")
+	msgCapabilitiesDropped         = message("DSMB-0020", log.InfoLevel, "capabilities dropped")
+	msgChaosModeEnabled            = message("DSMB-0021", log.WarnLevel, "chaos mode enabled")
+	msgChaosDelayingStart          = message("DSMB-0022", log.WarnLevel, "chaos: delaying start")
+	msgChaosFailingReload          = message("DSMB-0023", log.WarnLevel, "chaos: failing reload")
+	msgChaosHangingStop            = message("DSMB-0024", log.WarnLevel, "chaos: hanging stop")
+	msgNoCloudInstanceDetected     = message("DSMB-0025", log.InfoLevel, "no cloud instance detected")
+	msgCloudInstanceDetected       = message("DSMB-0026", log.InfoLevel, "cloud instance detected")
+	msgControlCommandReceived      = message("DSMB-0027", log.InfoLevel, "control command received")
+	msgUnableToCheckDisk           = message("DSMB-0028", log.WarnLevel, "Unable to check free disk space")
+	msgDiskSpaceRecovered          = message("DSMB-0029", log.InfoLevel, "disk space recovered")
+	msgDiskSpaceLow                = message("DSMB-0030", log.WarnLevel, "disk space low")
+	msgDiskSpaceCritical           = message("DSMB-0031", log.ErrorLevel, "disk space critical")
+	msgDraining                    = message("DSMB-0032", log.InfoLevel, "draining")
+	msgUnableToServe               = message("DSMB-0033", log.ErrorLevel, "Unable to serve")
+	msgFlagsChanged                = message("DSMB-0034", log.InfoLevel, "flags changed")
+	msgGarbageCollectorTuned       = message("DSMB-0035", log.InfoLevel, "garbage collector tuned")
+	msgComponentDisabled           = message("DSMB-0036", log.InfoLevel, "component disabled")
+	msgComponentAdded              = message("DSMB-0037", log.InfoLevel, "component added")
+	msgComponentRemoved            = message("DSMB-0038", log.InfoLevel, "component removed")
+	msgComponentEnabled            = message("DSMB-0039", log.InfoLevel, "component enabled")
+	msgRestartingFailedComponent   = message("DSMB-0040", log.WarnLevel, "restarting failed component")
+	msgComponentDegraded           = message("DSMB-0041", log.WarnLevel, "component degraded")
+	msgShutdownReport              = message("DSMB-0042", log.InfoLevel, "shutdown report")
+	msgStopHookFailed              = message("DSMB-0043", log.ErrorLevel, "OnStop hook failed")
+	msgSuspendHookFailed           = message("DSMB-0044", log.ErrorLevel, "OnSuspend hook failed")
+	msgResumeHookFailed            = message("DSMB-0045", log.ErrorLevel, "OnResume hook failed")
+	msgDiskHookFailed              = message("DSMB-0046", log.ErrorLevel, "OnDiskCritical hook failed")
+	msgUnableToRestrictFiles       = message("DSMB-0047", log.WarnLevel, "Unable to restrict filesystem access")
+	msgFilesRestricted             = message("DSMB-0048", log.InfoLevel, "filesystem access restricted")
+	msgGoroutineLeaked             = message("DSMB-0049", log.WarnLevel, "goroutine leaked")
+	msgClosingInheritedListener    = message("DSMB-0050", log.WarnLevel, "closing unused inherited listener")
+	msgHostShuttingDown            = message("DSMB-0051", log.InfoLevel, "host shutting down")
+	msgUnableToTakeInhibitor       = message("DSMB-0052", log.WarnLevel, "Unable to take shutdown inhibitor")
+	msgUnableToReleaseInhibitor    = message("DSMB-0053", log.WarnLevel, "Unable to release shutdown inhibitor")
+	msgDrainingLongLived           = message("DSMB-0054", log.InfoLevel, "draining long-lived connections")
+	msgUnableToNotifyLongLived     = message("DSMB-0055", log.WarnLevel, "Unable to notify long-lived connection")
+	msgMaxProcsFromEnv             = message("DSMB-0056", log.InfoLevel, "GOMAXPROCS set by environment, ignoring CPU quota")
+	msgUnableToReadCPUQuota        = message("DSMB-0057", log.WarnLevel, "Unable to read CPU quota")
+	msgMaxProcsWithoutQuota        = message("DSMB-0058", log.InfoLevel, "no CPU quota, GOMAXPROCS bounded by CPU affinity only")
+	msgMaxProcsFromQuota           = message("DSMB-0059", log.InfoLevel, "GOMAXPROCS set from CPU quota")
+	msgNetworkChangeSeen           = message("DSMB-0060", log.DebugLevel, "network changed")
+	msgUnableToWatchNetwork        = message("DSMB-0061", log.WarnLevel, "Unable to watch for network changes")
+	msgNetworkChanged              = message("DSMB-0062", log.InfoLevel, "network changed")
+	msgUnableToHandleNetworkChange = message("DSMB-0063", log.ErrorLevel, "Unable to handle network change")
+	msgUnableToResumeComponent     = message("DSMB-0064", log.WarnLevel, "Unable to resume component")
+	msgAdminStepRequested          = message("DSMB-0065", log.InfoLevel, "admin step requested")
+	msgUnableToCompleteAdminStep   = message("DSMB-0066", log.WarnLevel, "Unable to complete admin step")
+	msgHostSuspending              = message("DSMB-0067", log.InfoLevel, "host suspending")
+	msgHostResumed                 = message("DSMB-0068", log.InfoLevel, "host resumed")
+	msgUnableToWatchPower          = message("DSMB-0069", log.WarnLevel, "Unable to watch for suspend and resume")
+	msgPreflightFailed             = message("DSMB-0070", log.ErrorLevel, "pre-flight check failed")
+	msgNoNewPrivsSet               = message("DSMB-0071", log.InfoLevel, "no_new_privs set")
+	msgCoreDumpsConfigured         = message("DSMB-0072", log.InfoLevel, "core dumps configured")
+	msgStartupProgress             = message("DSMB-0073", log.InfoLevel, "startup progress")
+	msgUnableToNotifyManager       = message("DSMB-0074", log.WarnLevel, "Unable to notify service manager")
+	msgShutdownRehearsal           = message("DSMB-0075", log.InfoLevel, "shutdown rehearsal")
+	msgUnableToCheckRequirement    = message("DSMB-0076", log.WarnLevel, "Unable to check requirement")
+	msgUnableToRemoveRuntimeDir    = message("DSMB-0077", log.WarnLevel, "Unable to remove runtime directory")
+	msgSandboxed                   = message("DSMB-0078", log.InfoLevel, "sandboxed")
+	msgSeccompFilterInstalled      = message("DSMB-0079", log.InfoLevel, "seccomp filter installed")
+	msgTenantRemoved               = message("DSMB-0080", log.InfoLevel, "tenant removed")
+	msgTenantAdded                 = message("DSMB-0081", log.InfoLevel, "tenant added")
+	msgUnableToSendEvents          = message("DSMB-0082", log.WarnLevel, "Unable to send events")
+	msgSupervisedChildExited       = message("DSMB-0083", log.InfoLevel, "supervised child exited")
+	msgSupervisedChildFailed       = message("DSMB-0084", log.ErrorLevel, "supervised child failed")
+	msgRestartIntensityExceeded    = message("DSMB-0085", log.ErrorLevel, "supervisor restart intensity exceeded")
+	msgUnableToStopSupervisedChild = message("DSMB-0086", log.ErrorLevel, "Unable to stop supervised child")
+	msgComponentSwapped            = message("DSMB-0087", log.InfoLevel, "component swapped")
+	msgTimezonePinned              = message("DSMB-0088", log.InfoLevel, "timezone pinned")
+	msgUpgrading                   = message("DSMB-0089", log.InfoLevel, "upgrading")
+	msgUnableToNotifyParent        = message("DSMB-0090", log.WarnLevel, "Unable to notify parent of upgrade")
+	msgIncarnation                 = message("DSMB-0091", log.InfoLevel, "incarnation counted")
+	msgUnableToCountIncarnation    = message("DSMB-0092", log.WarnLevel, "Unable to count incarnation")
+	msgUpgraded                    = message("DSMB-0093", log.InfoLevel, "upgraded")
+	msgStopScheduled               = message("DSMB-0094", log.InfoLevel, "stop scheduled")
+	msgStopUnscheduled             = message("DSMB-0095", log.InfoLevel, "scheduled stop cancelled")
+	msgDrainingForStop             = message("DSMB-0096", log.InfoLevel, "draining for scheduled stop")
+	msgUnableToQuiesce             = message("DSMB-0097", log.WarnLevel, "Unable to quiesce lifecycle")
+	msgUnableToResume              = message("DSMB-0098", log.WarnLevel, "Unable to resume lifecycle")
+	msgUnableToCountWork           = message("DSMB-0099", log.WarnLevel, "Unable to count work")
+	msgMaxWorkReached              = message("DSMB-0100", log.InfoLevel, "maximum work reached")
+	msgUnableToReloadCerts         = message("DSMB-0101", log.ErrorLevel, "Unable to reload certificates")
+	msgListenerTLSChanged          = message("DSMB-0102", log.InfoLevel, "inherited listener TLS settings changed")
+	msgGoroutinePanicked           = message("DSMB-0103", log.ErrorLevel, "goroutine panicked")
+	msgGoroutinesStillRunning      = message("DSMB-0104", log.WarnLevel, "goroutines still running after stop")
+	msgStandby                     = message("DSMB-0105", log.InfoLevel, "standing by")
+	msgActivated                   = message("DSMB-0106", log.InfoLevel, "activated")
+	msgActivatorFailed             = message("DSMB-0107", log.WarnLevel, "Unable to await activation")
+	msgLeaseAcquired               = message("DSMB-0108", log.InfoLevel, "lease acquired")
+	msgLeaseLost                   = message("DSMB-0109", log.ErrorLevel, "lease lost, deactivating")
+	msgUnableToRestoreCounters     = message("DSMB-0110", log.WarnLevel, "Unable to restore counters")
+	msgUnableToScheduleReloads     = message("DSMB-0111", log.WarnLevel, "Unable to schedule reloads")
+	msgScheduledReload             = message("DSMB-0112", log.InfoLevel, "scheduled reload")
+	msgUnableToBakeReload          = message("DSMB-0113", log.WarnLevel, "Unable to bake reload")
+	msgBakingReload                = message("DSMB-0114", log.InfoLevel, "baking reload")
+	msgRollingBackReload           = message("DSMB-0115", log.ErrorLevel, "reload degraded health, rolling back")
+	msgUnableToRollBack            = message("DSMB-0116", log.ErrorLevel, "Unable to roll back reload")
+	msgStrictViolation             = message("DSMB-0117", log.ErrorLevel, "strict mode violation")
+	msgLocalePinned                = message("DSMB-0118", log.InfoLevel, "locale pinned")
+)
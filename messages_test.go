@@ -0,0 +1,22 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"testing"
+
+	log "github.com/uber-go/zap"
+)
+
+// BenchmarkEmit measures logging a catalog message with a field, as Wait does
+// for every signal.
+func BenchmarkEmit(b *testing.B) {
+	discardLogs(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msgSignalCaught.emit(
+			log.String("signal", "hangup"),
+		)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import "expvar"
+
+// Metrics holds the counters, gauges and status Dissembler exports. It is
+// published through expvar as "dissembler" and served with the other expvar
+// variables at /debug/vars by any server using http.DefaultServeMux.
+var Metrics = expvar.NewMap("dissembler")
+
+const (
+	// metricComponentsDegraded is the number of Group components currently
+	// degraded.
+	metricComponentsDegraded = "components_degraded"
+	// metricStartupProgress is the most recent startup progress report.
+	metricStartupProgress = "startup_progress"
+	// metricDrainRemaining is the number of in-flight units remaining while
+	// stopping.
+	metricDrainRemaining = "drain_remaining"
+	// metricCPUAffinity is the CPU affinity applied by WithCPUAffinity.
+	metricCPUAffinity = "cpu_affinity"
+	// metricGC is the garbage collector configuration.
+	metricGC = "gc"
+	// metricState is the State of the Dissembler serving.
+	metricState = "state"
+	// metricEventsDropped is the number of events dropped because a
+	// subscriber's queue was full.
+	metricEventsDropped = "events_dropped"
+	// metricSignals are the statistics of signals received.
+	metricSignals = "signals"
+	// metricGeneration is the configuration generation.
+	metricGeneration = "config_generation"
+	// metricDiskFree is the free space on each path watched by WatchDisk.
+	metricDiskFree = "disk_free"
+	// metricRunID is the ID of the current run.
+	metricRunID = "run_id"
+	// metricIncarnation is how many times the service has started, as
+	// counted in the runtime directory.
+	metricIncarnation = "incarnation"
+	// metricRole is "standby" or "active" for a lifecycle in warm standby.
+	metricRole = "role"
+	// metricRestarts is how many times the lifecycle has been handed over to
+	// a new process through an upgrade.
+	metricRestarts = "restarts_total"
+	// metricSignalsTotal is the number of signals received, across upgrades.
+	metricSignalsTotal = "signals_total"
+)
@@ -0,0 +1,169 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+// Package natssink provides a dissembler.EventSink publishing lifecycle events
+// to a NATS subject as JSON, speaking the NATS client protocol directly so no
+// client library is required.
+//
+//	sink := natssink.New("nats.internal:4222", "dissembler.events")
+//	defer sink.Close()
+//	dissembler.Serve(lc, dissembler.WithEventSink(sink))
+package natssink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dissembler/dissembler"
+)
+
+// DefaultTimeout bounds connecting and publishing when Timeout is not set.
+const DefaultTimeout = 5 * time.Second
+
+// Sink publishes each event as a JSON message on Subject. It connects on the
+// first Send and reconnects on the Send following a failure.
+type Sink struct {
+	// Addr is the NATS server address, such as "localhost:4222".
+	Addr string
+	// Subject is the subject events are published on.
+	Subject string
+	// Timeout bounds connecting and publishing each batch. If zero,
+	// DefaultTimeout is used.
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// New returns a Sink publishing on subject at the NATS server addr.
+func New(addr, subject string) *Sink {
+	return &Sink{Addr: addr, Subject: subject}
+}
+
+// Send publishes events, one message each.
+func (s *Sink) Send(events []dissembler.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+
+	for _, e := range events {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.w, "PUB %s %d\r\n", s.Subject, len(msg))
+		s.w.Write(msg)
+		s.w.WriteString("\r\n")
+	}
+	if err := s.w.Flush(); err != nil {
+		s.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the server.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+// closeLocked closes the connection, if any. s.mu must be held.
+func (s *Sink) closeLocked() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.w = nil, nil
+	return err
+}
+
+// connect dials the server and completes the protocol handshake. s.mu must
+// be held.
+func (s *Sink) connect() error {
+	if strings.ContainsAny(s.Subject, " \t\r\n") || s.Subject == "" {
+		return fmt.Errorf("invalid NATS subject %q", s.Subject)
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Addr, s.timeout())
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout()))
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	w := bufio.NewWriter(conn)
+	w.WriteString(`CONNECT {"verbose":false,"pedantic":false,"name":"dissembler"}` + "\r\n")
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	s.conn, s.w = conn, w
+	go s.read(conn, r)
+	return nil
+}
+
+// read answers the server's keepalive pings on conn until it is closed,
+// closing the connection if the server reports an error.
+func (s *Sink) read(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			s.mu.Lock()
+			if s.conn == conn {
+				s.w.WriteString("PONG\r\n")
+				s.w.Flush()
+			}
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			err = errors.New(strings.TrimSpace(line))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	if s.conn == conn {
+		s.closeLocked()
+	}
+	s.mu.Unlock()
+}
+
+// timeout returns Timeout, or DefaultTimeout if it is not set.
+func (s *Sink) timeout() time.Duration {
+	if s.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return s.Timeout
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// NetworkSettle is how long the network must be quiet after a change before
+// WatchNetwork acts on it, so the burst of changes made by a DHCP lease or
+// VPN connecting is handled once.
+const NetworkSettle = time.Second
+
+// WatchNetwork watches for network interfaces and addresses changing, such as
+// by DHCP or a VPN, so a daemon binding to dynamic addresses can rebind
+// without a restart. Once changes have settled for NetworkSettle, fn is called
+// with the Serve context; if fn is nil, the lifecycle is reloaded as if sent
+// SIGHUP instead. Errors returned by fn are logged.
+//
+// Changes are received over netlink, so are only supported on Linux;
+// elsewhere a warning is logged and fn is never called.
+func WatchNetwork(fn func(ctx context.Context) error) Option {
+	return func(d *Dissembler) {
+		d.watchNet = true
+		d.onNetwork = fn
+	}
+}
+
+// watchNetwork acts on network changes if enabled by WatchNetwork, until
+// Serve returns.
+func (d *Dissembler) watchNetwork() {
+	if !d.watchNet {
+		return
+	}
+	changes := make(chan struct{}, 1)
+	err := watchNetwork(d.quit, func(change string) {
+		msgNetworkChangeSeen.emit(log.String("change", change))
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		msgUnableToWatchNetwork.emit(
+			log.String("error", err.Error()),
+		)
+		return
+	}
+	go d.settleNetwork(changes)
+}
+
+// settleNetwork handles each burst of changes once it has settled.
+func (d *Dissembler) settleNetwork(changes <-chan struct{}) {
+	clock := clockOr(d.clock)
+	for {
+		select {
+		case <-changes:
+		case <-d.quit:
+			return
+		}
+	settle:
+		for {
+			select {
+			case <-changes:
+			case <-clock.After(NetworkSettle):
+				break settle
+			case <-d.quit:
+				return
+			}
+		}
+
+		msgNetworkChanged.emit()
+		var err error
+		if d.onNetwork != nil {
+			err = d.onNetwork(context.Background())
+		} else {
+			err = d.request(context.Background(), syscall.SIGHUP)
+		}
+		if err != nil && err != errNotServing {
+			msgUnableToHandleNetworkChange.emit(
+				log.String("error", err.Error()),
+			)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"syscall"
+)
+
+// Netlink route multicast groups, which the syscall package does not define.
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4Ifaddr = 0x10
+	rtmgrpIPv6Ifaddr = 0x100
+)
+
+// watchNetwork subscribes to link and address changes over netlink, calling
+// changed with a description of each, until quit is closed.
+func watchNetwork(quit <-chan struct{}, changed func(string)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIPv4Ifaddr | rtmgrpIPv6Ifaddr,
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("bind", err)
+	}
+	// A non-blocking descriptor is read through the runtime poller, so
+	// closing the file unblocks the read.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("setnonblock", err)
+	}
+	f := os.NewFile(uintptr(fd), "netlink")
+
+	go func() {
+		<-quit
+		f.Close()
+	}()
+	go func() {
+		buf := make([]byte, os.Getpagesize())
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				if err == syscall.ENOBUFS {
+					// Notifications were dropped; something changed.
+					changed("notifications overrun")
+					continue
+				}
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, m := range msgs {
+				if change, ok := netlinkChanges[m.Header.Type]; ok {
+					changed(change)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// netlinkChanges describes the netlink messages acted on.
+var netlinkChanges = map[uint16]string{
+	syscall.RTM_NEWLINK: "link added or changed",
+	syscall.RTM_DELLINK: "link removed",
+	syscall.RTM_NEWADDR: "address added",
+	syscall.RTM_DELADDR: "address removed",
+}
@@ -0,0 +1,14 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import "errors"
+
+// watchNetwork is not supported outside Linux.
+func watchNetwork(quit <-chan struct{}, changed func(string)) error {
+	return errors.New("network change notifications require netlink")
+}
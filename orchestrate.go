@@ -0,0 +1,264 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// ErrQuiesceUnsupported is returned when quiescing a lifecycle that does not
+// implement Quiescer.
+var ErrQuiesceUnsupported = errors.New("lifecycle does not support quiescing")
+
+// Quiescer is an optional interface that may be implemented by a Lifecycle to
+// stop taking on new work, such as by failing health checks or pausing
+// consumers, while finishing the work it has and keeping its listeners open.
+// Resume undoes Quiesce. It backs the quiesce and resume steps an orchestrator
+// drives over the admin API around an upgrade.
+type Quiescer interface {
+	Quiesce(ctx context.Context) error
+	Resume(ctx context.Context) error
+}
+
+// Quiesce quiesces every running component implementing Quiescer, in the order
+// Stop would stop them. If a component fails to quiesce, those already
+// quiesced are resumed.
+func (g *Group) Quiesce(ctx context.Context) error {
+	components := g.quiescers()
+
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if err := c.lc.(Quiescer).Quiesce(ctx); err != nil {
+			for _, c := range components[i+1:] {
+				if err := c.lc.(Quiescer).Resume(ctx); err != nil {
+					msgUnableToResumeComponent.emit(
+						log.String("component", c.name),
+						log.String("error", err.Error()),
+					)
+				}
+			}
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// Resume resumes every running component implementing Quiescer, in the order
+// Start started them, returning the first error once all have been resumed.
+func (g *Group) Resume(ctx context.Context) error {
+	var first error
+	for _, c := range g.quiescers() {
+		if err := c.lc.(Quiescer).Resume(ctx); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	return first
+}
+
+// quiescers returns the running components implementing Quiescer.
+func (g *Group) quiescers() []*component {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var components []*component
+	for _, c := range g.components {
+		if _, ok := c.lc.(Quiescer); ok && c.loadStatus().state == ComponentRunning {
+			components = append(components, c)
+		}
+	}
+	return components
+}
+
+// StepResult is the result of one step of an upgrade driven over the admin
+// API, describing the process that answered so an orchestrator can tell
+// whether the new one took over.
+type StepResult struct {
+	// Step is the step taken: quiesce, upgrade, verify or resume.
+	Step string `json:"step"`
+	// OK reports whether the step succeeded.
+	OK bool `json:"ok"`
+	// Error describes why the step failed.
+	Error string `json:"error,omitempty"`
+	// Duration is how long the step took.
+	Duration time.Duration `json:"duration_ns"`
+	// PID, Version, State and Generation describe the answering process.
+	PID        int    `json:"pid"`
+	Version    string `json:"version"`
+	State      string `json:"state"`
+	Generation uint64 `json:"generation"`
+	// Quiesced reports whether the answering process is quiesced.
+	Quiesced bool `json:"quiesced"`
+	// Successor is the process started by a successful upgrade step.
+	Successor int `json:"successor,omitempty"`
+}
+
+// WithQuiescedReload quiesces the lifecycle around every reload, if it
+// implements Quiescer, so streaming components pause intake and let in-flight
+// items settle before the new configuration is applied, then resume once it
+// has been, whether or not the reload succeeded. If the lifecycle fails to
+// quiesce, the reload fails and the current configuration is kept. A
+// lifecycle already quiesced, such as by an orchestrator, stays quiesced.
+func WithQuiescedReload() Option {
+	return func(d *Dissembler) {
+		d.quiescedReload = true
+	}
+}
+
+// quiesceForReload quiesces the lifecycle for a reload if WithQuiescedReload
+// is given, returning the function resuming it once the reload is done.
+func (d *Dissembler) quiesceForReload(ctx context.Context) (resume func(), err error) {
+	if _, ok := d.lifecycle.(Quiescer); !ok || !d.quiescedReload || atomic.LoadInt32(&d.quiesced) == 1 {
+		return func() {}, nil
+	}
+	if err := d.quiesce(ctx); err != nil {
+		msgUnableToQuiesce.emit(
+			log.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return func() {
+		if err := d.resume(context.Background()); err != nil {
+			msgUnableToResume.emit(
+				log.String("error", err.Error()),
+			)
+		}
+	}, nil
+}
+
+// quiesce quiesces the lifecycle, doing nothing if it is already quiesced.
+func (d *Dissembler) quiesce(ctx context.Context) error {
+	d.quiesceMu.Lock()
+	defer d.quiesceMu.Unlock()
+	if atomic.LoadInt32(&d.quiesced) == 1 {
+		return nil
+	}
+	q, ok := d.lifecycle.(Quiescer)
+	if !ok {
+		return ErrQuiesceUnsupported
+	}
+	if err := q.Quiesce(ctx); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&d.quiesced, 1)
+	return nil
+}
+
+// resume resumes the lifecycle, doing nothing if it is not quiesced.
+func (d *Dissembler) resume(ctx context.Context) error {
+	d.quiesceMu.Lock()
+	defer d.quiesceMu.Unlock()
+	if atomic.LoadInt32(&d.quiesced) == 0 {
+		return nil
+	}
+	if err := d.lifecycle.(Quiescer).Resume(ctx); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&d.quiesced, 0)
+	return nil
+}
+
+// verify reports whether the process is running and ready and, if want is
+// not empty, running version want.
+func (d *Dissembler) verify(want string) error {
+	if s := d.State(); s != StateRunning {
+		return fmt.Errorf("not running: %s", s)
+	}
+	if want != "" && want != fullVersion() {
+		return fmt.Errorf("running version %s, not %s", fullVersion(), want)
+	}
+	if rc, ok := d.lifecycle.(ReadyChecker); ok {
+		if err := rc.Ready(); err != nil {
+			return fmt.Errorf("not ready: %w", err)
+		}
+	}
+	return nil
+}
+
+// adminStep serves one upgrade step, run by step, as a POST endpoint.
+func (d *Dissembler) adminStep(name string, step func(ctx context.Context, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAdmin(w, http.StatusMethodNotAllowed, adminResponse{
+				Error: http.StatusText(http.StatusMethodNotAllowed),
+			})
+			return
+		}
+
+		// Steps outlive the request's context, so only its traceparent is
+		// carried over.
+		ctx, err := withTraceParent(context.Background(), r.Header.Get("traceparent"))
+		if err != nil {
+			writeAdmin(w, http.StatusBadRequest, adminResponse{Error: err.Error()})
+			return
+		}
+
+		msgAdminStepRequested.emit(
+			log.String("step", name),
+			log.String("remote", r.RemoteAddr),
+			log.String("traceparent", r.Header.Get("traceparent")),
+		)
+		clock := clockOr(d.clock)
+		begin := clock.Now()
+		err = step(ctx, r)
+		res := StepResult{
+			Step:       name,
+			OK:         err == nil,
+			Duration:   clock.Now().Sub(begin),
+			PID:        os.Getpid(),
+			Version:    fullVersion(),
+			State:      d.State().String(),
+			Generation: d.Generation(),
+			Quiesced:   atomic.LoadInt32(&d.quiesced) == 1,
+		}
+
+		code := http.StatusOK
+		switch {
+		case err == nil:
+			if name == "upgrade" {
+				res.Successor = d.successor
+			}
+		case errors.Is(err, ErrQuiesceUnsupported):
+			code = http.StatusNotImplemented
+		case errors.Is(err, errNotServing):
+			code = http.StatusServiceUnavailable
+		default:
+			code = http.StatusUnprocessableEntity
+		}
+		if err != nil {
+			res.Error = err.Error()
+			msgUnableToCompleteAdminStep.emit(
+				log.String("step", name),
+				log.String("error", err.Error()),
+			)
+		}
+		writeAdmin(w, code, res)
+	}
+}
+
+// adminQuiesce, adminUpgrade, adminVerify and adminResume are the steps of an
+// upgrade driven over the admin API.
+func (d *Dissembler) adminQuiesce(ctx context.Context, r *http.Request) error {
+	return d.quiesce(ctx)
+}
+
+func (d *Dissembler) adminUpgrade(ctx context.Context, r *http.Request) error {
+	return d.request(ctx, syscall.SIGUSR2)
+}
+
+func (d *Dissembler) adminVerify(ctx context.Context, r *http.Request) error {
+	return d.verify(r.URL.Query().Get("version"))
+}
+
+func (d *Dissembler) adminResume(ctx context.Context, r *http.Request) error {
+	return d.resume(ctx)
+}
@@ -0,0 +1,82 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"net"
+	"sync"
+)
+
+// PacketServer adapts a packet-oriented server, such as a UDP or unixgram
+// service, to the Lifecycle interface. Init opens the connection through
+// ListenPacket, so it survives upgrades, Start runs Serve on it, and Stop
+// closes the connection and waits for Serve to return.
+type PacketServer struct {
+	// Network is the packet network, such as "udp" or "unixgram".
+	Network string
+	// Address is the local address to listen on.
+	Address string
+	// Serve reads and handles packets from conn until conn is closed.
+	Serve func(conn net.PacketConn) error
+
+	mu       sync.Mutex
+	conn     net.PacketConn
+	started  bool
+	stopping bool
+	done     chan struct{}
+}
+
+// Init opens the packet connection.
+func (p *PacketServer) Init() error {
+	conn, err := ListenPacket(p.Network, p.Address)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.conn, p.started, p.stopping = conn, false, false
+	p.done = make(chan struct{})
+	p.mu.Unlock()
+	return nil
+}
+
+// Start runs Serve until the connection is closed. Errors caused by Stop
+// closing the connection are not returned.
+func (p *PacketServer) Start() error {
+	p.mu.Lock()
+	if p.stopping {
+		p.mu.Unlock()
+		return nil
+	}
+	p.started = true
+	conn, done := p.conn, p.done
+	p.mu.Unlock()
+	defer close(done)
+
+	err := p.Serve(conn)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopping {
+		return nil
+	}
+	return err
+}
+
+// Stop closes the connection and waits for Serve to return.
+func (p *PacketServer) Stop() error {
+	p.mu.Lock()
+	p.stopping = true
+	conn, started, done := p.conn, p.started, p.done
+	p.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	if started {
+		<-done
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Phase is a lifecycle method.
+type Phase int
+
+const (
+	// PhaseInit is Init.
+	PhaseInit Phase = iota
+	// PhaseStart is Start.
+	PhaseStart
+	// PhaseReload is Reload or ReloadContext.
+	PhaseReload
+	// PhaseStop is Stop or StopContext.
+	PhaseStop
+)
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseInit:
+		return "init"
+	case PhaseStart:
+		return "start"
+	case PhaseReload:
+		return "reload"
+	case PhaseStop:
+		return "stop"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// PhaseError is the error returned by a lifecycle method, with the context
+// error reporters need to tag the failure. Errors returned by Serve, Group
+// and Supervisor for failed lifecycle methods can be inspected with
+// errors.As.
+type PhaseError struct {
+	// Component names the failed component within a Group or Supervisor,
+	// joined with "/" for nested ones. It is empty for the lifecycle itself.
+	Component string
+	// Phase is the lifecycle method that failed.
+	Phase Phase
+	// Attempt counts the calls to the method, from 1. It exceeds 1 only for
+	// components that were restarted.
+	Attempt int
+	// Elapsed is how long the method ran before failing.
+	Elapsed time.Duration
+	// Err is the error the method returned.
+	Err error
+}
+
+// Error describes the failure in the form "component NAME: PHASE: ERR".
+func (e *PhaseError) Error() string {
+	phase := e.Phase.String()
+	if e.Attempt > 1 {
+		phase = fmt.Sprintf("%s (attempt %d)", phase, e.Attempt)
+	}
+	if e.Component == "" {
+		return fmt.Sprintf("%s: %v", phase, e.Err)
+	}
+	return fmt.Sprintf("component %s: %s: %v", e.Component, phase, e.Err)
+}
+
+// Unwrap returns the error the method returned.
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// phaseError wraps err, returned by phase of the named component, in a
+// PhaseError. If err is already a PhaseError, as returned by a nested Group,
+// its component is qualified by name instead, so the innermost failure is the
+// one reported. It returns nil if err is nil.
+func phaseError(name string, phase Phase, attempt int, elapsed time.Duration, err error) error {
+	if err == nil {
+		return nil
+	}
+	if pe, ok := err.(*PhaseError); ok {
+		if name == "" {
+			return pe
+		}
+		qualified := *pe
+		qualified.Component = name
+		if pe.Component != "" {
+			qualified.Component += "/" + pe.Component
+		}
+		return &qualified
+	}
+	return &PhaseError{
+		Component: name,
+		Phase:     phase,
+		Attempt:   attempt,
+		Elapsed:   elapsed,
+		Err:       err,
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+
+	log "github.com/uber-go/zap"
+)
+
+// OnSuspend registers a named hook, such as pausing a job queue or closing
+// connections that will not survive sleep, to run when the host is about to
+// suspend. Suspend hooks run in reverse order of registration, each within
+// its own budget, and the host's sleep is delayed until they return, up to
+// logind's InhibitDelayMaxSec.
+//
+// Suspend and resume events are received from systemd-logind, so are only
+// supported on Linux; elsewhere a warning is logged and the hooks never run.
+func OnSuspend(name string, fn func(ctx context.Context) error, opts ...HookOption) Option {
+	return func(d *Dissembler) {
+		d.suspendHooks = append(d.suspendHooks, newHook(name, fn, opts))
+	}
+}
+
+// OnResume registers a named hook, such as resuming a job queue, to run when
+// the host wakes from suspend. Resume hooks run in order of registration, each
+// within its own budget.
+func OnResume(name string, fn func(ctx context.Context) error, opts ...HookOption) Option {
+	return func(d *Dissembler) {
+		d.resumeHooks = append(d.resumeHooks, newHook(name, fn, opts))
+	}
+}
+
+// watchPower runs the OnSuspend and OnResume hooks as the host sleeps and
+// wakes, until Serve returns.
+func (d *Dissembler) watchPower() {
+	if len(d.suspendHooks) == 0 && len(d.resumeHooks) == 0 {
+		return
+	}
+	err := watchSleep(d.quit, func() {
+		msgHostSuspending.emit()
+		for i := len(d.suspendHooks) - 1; i >= 0; i-- {
+			d.runHook(msgSuspendHookFailed, d.suspendHooks[i])
+		}
+	}, func() {
+		msgHostResumed.emit()
+		for _, h := range d.resumeHooks {
+			d.runHook(msgResumeHookFailed, h)
+		}
+	})
+	if err != nil {
+		msgUnableToWatchPower.emit(
+			log.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	log "github.com/uber-go/zap"
+)
+
+// ErrAddrInUse is matched by errors.Is for an AddrInUseError.
+var ErrAddrInUse = errors.New("address already in use")
+
+// AddrInUseError is returned by Serve when a listen address checked with
+// WithListenCheck is already bound by another process. On Linux the process
+// holding the address is identified when permissions allow.
+type AddrInUseError struct {
+	Network string
+	Address string
+	// PID and Process identify the process holding the address, if known.
+	PID     int
+	Process string
+}
+
+// Error describes the conflict and, if known, the process holding the address.
+func (e *AddrInUseError) Error() string {
+	msg := fmt.Sprintf("%s %s: %v", e.Network, e.Address, ErrAddrInUse)
+	if e.PID != 0 {
+		msg += fmt.Sprintf(" by %s (pid %d)", e.Process, e.PID)
+	}
+	return msg
+}
+
+// Is reports whether target is ErrAddrInUse.
+func (e *AddrInUseError) Is(target error) bool {
+	return target == ErrAddrInUse
+}
+
+// preflightCheck is a named check run before Init.
+type preflightCheck struct {
+	name string
+	fn   func() error
+}
+
+// preflight runs the pre-flight checks in order, returning the first failure.
+func (d *Dissembler) preflight() error {
+	if len(d.checks) == 0 {
+		return nil
+	}
+
+	end := BootStep("preflight")
+	defer end()
+	for _, c := range d.checks {
+		if err := c.fn(); err != nil {
+			msgPreflightFailed.emit(
+				log.String("check", c.name),
+				log.String("error", err.Error()),
+			)
+			return err
+		}
+	}
+	return nil
+}
+
+// WithListenCheck probes, before Init, that the address can be bound on the
+// TCP or UDP network, failing Serve with an AddrInUseError naming the process
+// holding it if not. Addresses handed down by an upgrade are not probed.
+func WithListenCheck(network, address string) Option {
+	return func(d *Dissembler) {
+		d.checks = append(d.checks, preflightCheck{
+			name: "listen " + network + " " + address,
+			fn: func() error {
+				return probeAddr(network, address)
+			},
+		})
+	}
+}
+
+// probeAddr binds and releases the address.
+func probeAddr(network, address string) error {
+	listeners.mu.Lock()
+	_, inherited := listeners.inherited[key(network, address)]
+	listeners.mu.Unlock()
+	if inherited {
+		return nil
+	}
+
+	var err error
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		var ln net.Listener
+		if ln, err = net.Listen(network, address); err == nil {
+			ln.Close()
+		}
+	case "udp", "udp4", "udp6":
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket(network, address); err == nil {
+			conn.Close()
+		}
+	default:
+		return fmt.Errorf("listen check: unsupported network %s", network)
+	}
+
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		return err
+	}
+	e := &AddrInUseError{Network: network, Address: address}
+	if _, p, serr := net.SplitHostPort(address); serr == nil {
+		if port, perr := strconv.Atoi(p); perr == nil {
+			e.PID, e.Process = addrOwner(network, port)
+		}
+	}
+	return e
+}
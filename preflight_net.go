@@ -0,0 +1,114 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// preflightTimeout bounds each network pre-flight check.
+	preflightTimeout = 5 * time.Second
+	// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+	// the Unix epoch (1970).
+	ntpEpochOffset = 2208988800
+)
+
+// WithDNSCheck verifies, before Init, that each host name resolves, so
+// misconfigured or unreachable endpoints fail at boot rather than on first
+// use.
+func WithDNSCheck(hosts ...string) Option {
+	return func(d *Dissembler) {
+		for _, host := range hosts {
+			host := host
+			d.checks = append(d.checks, preflightCheck{
+				name: "dns " + host,
+				fn: func() error {
+					return checkDNS(host)
+				},
+			})
+		}
+	}
+}
+
+// checkDNS resolves host.
+func checkDNS(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("resolving %s: no addresses", host)
+	}
+	return nil
+}
+
+// WithClockCheck verifies, before Init, that the system clock is within
+// maxSkew of the time reported by the NTP server, such as "pool.ntp.org:123",
+// so certificate validation and token expiry do not fail mysteriously later.
+func WithClockCheck(server string, maxSkew time.Duration) Option {
+	return func(d *Dissembler) {
+		d.checks = append(d.checks, preflightCheck{
+			name: "clock " + server,
+			fn: func() error {
+				skew, err := clockSkew(server)
+				if err != nil {
+					return fmt.Errorf("querying %s: %w", server, err)
+				}
+				if skew > maxSkew || skew < -maxSkew {
+					return fmt.Errorf("system clock is %s off from %s, more than %s allowed",
+						skew, server, maxSkew)
+				}
+				return nil
+			},
+		})
+	}
+}
+
+// clockSkew returns the offset of the NTP server's clock from the system
+// clock, measured with a single SNTP request.
+func clockSkew(server string) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", server, preflightTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(preflightTimeout))
+
+	// Leap indicator 0, version 4, mode 3 (client).
+	req := make([]byte, 48)
+	req[0] = 0x23
+
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, err
+	}
+	received := time.Now()
+
+	if resp[0]&0x07 != 4 {
+		return 0, fmt.Errorf("unexpected NTP mode %d", resp[0]&0x07)
+	}
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
@@ -0,0 +1,73 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"syscall"
+
+	log "github.com/uber-go/zap"
+)
+
+// WithNoNewPrivs sets no_new_privs before Init, so neither the process nor
+// anything it executes can gain privileges through setuid binaries or file
+// capabilities, as NoNewPrivileges= does in a systemd unit. It is supported
+// on Linux for binaries built without cgo, since it applies to each thread;
+// otherwise Serve fails.
+func WithNoNewPrivs() Option {
+	return func(d *Dissembler) {
+		d.noNewPrivs = true
+	}
+}
+
+// WithCoreDumps explicitly enables or disables core dumps before Init,
+// regardless of the limits inherited from the environment.
+//
+// Disabling sets RLIMIT_CORE to zero and, on Linux, clears the dumpable flag,
+// so secrets in memory are never written to disk; this also prevents
+// unprivileged processes from attaching to the process with ptrace.
+// Enabling raises RLIMIT_CORE to its hard limit, sets the dumpable flag on
+// Linux, and sets the traceback level to crash so a fatal Go error dumps
+// core.
+func WithCoreDumps(enabled bool) Option {
+	return func(d *Dissembler) {
+		d.coreDumps = &enabled
+	}
+}
+
+// lockDown applies WithNoNewPrivs and WithCoreDumps.
+func (d *Dissembler) lockDown() error {
+	if d.noNewPrivs {
+		if err := setNoNewPrivs(); err != nil {
+			return fmt.Errorf("setting no_new_privs: %w", err)
+		}
+		msgNoNewPrivsSet.emit()
+	}
+
+	if d.coreDumps == nil {
+		return nil
+	}
+	enabled := *d.coreDumps
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &lim); err != nil {
+		return os.NewSyscallError("getrlimit", err)
+	}
+	lim.Cur = 0
+	if enabled {
+		lim.Cur = lim.Max
+		debug.SetTraceback("crash")
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &lim); err != nil {
+		return os.NewSyscallError("setrlimit", err)
+	}
+	if err := setDumpable(enabled); err != nil {
+		return err
+	}
+	msgCoreDumpsConfigured.emit(
+		log.Bool("enabled", enabled),
+	)
+	return nil
+}
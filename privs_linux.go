@@ -0,0 +1,32 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"syscall"
+)
+
+// prSetDumpable is PR_SET_DUMPABLE.
+const prSetDumpable = 4
+
+// setNoNewPrivs sets no_new_privs on every thread.
+func setNoNewPrivs() error {
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return allThreadsError("prctl", errno)
+	}
+	return nil
+}
+
+// setDumpable sets the dumpable flag, which belongs to the process.
+func setDumpable(dumpable bool) error {
+	var arg uintptr
+	if dumpable {
+		arg = 1
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetDumpable, arg, 0); errno != 0 {
+		return os.NewSyscallError("prctl", errno)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import "errors"
+
+// setNoNewPrivs is not supported outside Linux.
+func setNoNewPrivs() error {
+	return errors.New("no_new_privs requires Linux")
+}
+
+// setDumpable does nothing outside Linux, where RLIMIT_CORE alone governs
+// core dumps.
+func setDumpable(dumpable bool) error {
+	return nil
+}
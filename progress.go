@@ -0,0 +1,35 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"fmt"
+
+	"github.com/dissembler/dissembler/progress"
+	log "github.com/uber-go/zap"
+)
+
+func init() {
+	Metrics.Set(metricStartupProgress, expvar.Func(func() interface{} {
+		return progress.Current()
+	}))
+	progress.Subscribe(reportProgress)
+}
+
+// reportProgress surfaces a startup progress report in the log and as a
+// systemd status notification.
+func reportProgress(p progress.Progress) {
+	msgStartupProgress.emit(
+		log.String("stage", p.Stage),
+		log.Float64("percent", p.Percent),
+	)
+
+	status := fmt.Sprintf("STATUS=%s (%.0f%%)", p.Stage, p.Percent)
+	if err := sdNotify(status); err != nil {
+		msgUnableToNotifyManager.emit(
+			log.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+// Package progress reports the progress of slow startup work, such as loading
+// indexes or warming caches during Init, so operators can see that a boot is
+// advancing rather than hung.
+//
+// Dissembler subscribes to progress reports and surfaces them in its logs, its
+// exported variables, and systemd STATUS= notifications.
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is a report of how far startup has advanced.
+type Progress struct {
+	// Stage names the work in progress, such as "loading index".
+	Stage string `json:"stage"`
+	// Percent is how complete the stage is, from 0 to 100.
+	Percent float64 `json:"percent"`
+	// Time is when the progress was reported.
+	Time time.Time `json:"time"`
+}
+
+var (
+	mu          sync.Mutex
+	current     Progress
+	subscribers []func(Progress)
+)
+
+// Report records that stage is pct percent complete and passes the report to
+// every subscriber. Percentages are clamped to the range 0 to 100.
+func Report(stage string, pct float64) {
+	switch {
+	case pct < 0:
+		pct = 0
+	case pct > 100:
+		pct = 100
+	}
+	p := Progress{Stage: stage, Percent: pct, Time: time.Now()}
+
+	mu.Lock()
+	current = p
+	subs := subscribers
+	mu.Unlock()
+
+	for _, fn := range subs {
+		fn(p)
+	}
+}
+
+// Current returns the most recent report, or the zero Progress if nothing has
+// been reported.
+func Current() Progress {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Subscribe registers fn to be called with every subsequent report. fn is
+// called synchronously from Report and must not block.
+func Subscribe(fn func(Progress)) {
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers = append(subscribers[:len(subscribers):len(subscribers)], fn)
+}
@@ -0,0 +1,216 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProxyHeaderTimeout bounds how long a connection accepted with
+// ProxyProtocol may take to send its PROXY header if no timeout is given.
+const DefaultProxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature opens every PROXY protocol version 2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// errNoProxyHeader is returned reading a connection that did not open with a
+// PROXY header.
+var errNoProxyHeader = errors.New("proxy protocol: no PROXY header")
+
+// ProxyProtocol makes the listener expect a PROXY protocol header, version 1
+// or 2, at the start of every accepted connection, as sent by HAProxy, AWS
+// Network Load Balancers and others, so the connection's RemoteAddr and
+// LocalAddr are those of the client and the address it connected to instead
+// of the proxy's. The header is read on the connection's first use rather than
+// by Accept, waiting at most timeout, or DefaultProxyHeaderTimeout if zero.
+// Connections without a valid header fail to read.
+//
+// Headers are trusted as sent, so the listener must only be reachable through
+// the proxy.
+func ProxyProtocol(timeout time.Duration) ListenOption {
+	return func(c *listenConfig) {
+		if timeout <= 0 {
+			timeout = DefaultProxyHeaderTimeout
+		}
+		c.proxyTimeout = timeout
+	}
+}
+
+// proxyConn is a connection opening with a PROXY header, read on first use.
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once     sync.Once
+	src, dst net.Addr
+	err      error
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// newProxyConn returns conn reading its PROXY header within timeout.
+func newProxyConn(conn net.Conn, timeout time.Duration) *proxyConn {
+	return &proxyConn{Conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+}
+
+// header reads the PROXY header once, within the header timeout or the read
+// deadline set on the connection, whichever comes first.
+func (c *proxyConn) header() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		deadline := c.deadline
+		c.mu.Unlock()
+		limit := time.Now().Add(c.timeout)
+		if deadline.IsZero() || limit.Before(deadline) {
+			c.Conn.SetReadDeadline(limit)
+		}
+		c.src, c.dst, c.err = readProxyHeader(c.r)
+		c.Conn.SetReadDeadline(deadline)
+	})
+	return c.err
+}
+
+// Read reads data following the PROXY header.
+func (c *proxyConn) Read(b []byte) (int, error) {
+	if err := c.header(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the client's address given by the PROXY header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.header() == nil && c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to given by the PROXY
+// header.
+func (c *proxyConn) LocalAddr() net.Addr {
+	if c.header() == nil && c.dst != nil {
+		return c.dst
+	}
+	return c.Conn.LocalAddr()
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *proxyConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline.
+func (c *proxyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readProxyHeader reads a PROXY header of either version, returning the
+// source and destination addresses it carries. Both are nil for headers
+// carrying no addresses, such as health checks from the proxy itself.
+func readProxyHeader(r *bufio.Reader) (src, dst net.Addr, err error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, nil, fmt.Errorf("proxy protocol: %w", err)
+	}
+	switch {
+	case bytes.Equal(sig, proxyV2Signature):
+		return readProxyV2(r)
+	case bytes.HasPrefix(sig, []byte("PROXY ")):
+		return readProxyV1(r)
+	}
+	return nil, nil, errNoProxyHeader
+}
+
+// readProxyV1 reads a version 1, human-readable, PROXY header such as
+// "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n".
+func readProxyV1(r *bufio.Reader) (src, dst net.Addr, err error) {
+	const maxLen = 107
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == maxLen {
+			return nil, nil, errors.New("proxy protocol: v1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, fmt.Errorf("proxy protocol: %w", err)
+		}
+		line = append(line, b)
+	}
+
+	f := strings.Fields(string(line))
+	if len(f) >= 2 && f[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(f) != 6 || (f[1] != "TCP4" && f[1] != "TCP6") {
+		return nil, nil, fmt.Errorf("proxy protocol: invalid v1 header %q", strings.TrimSpace(string(line)))
+	}
+	srcIP, dstIP := net.ParseIP(f[2]), net.ParseIP(f[3])
+	srcPort, err1 := strconv.ParseUint(f[4], 10, 16)
+	dstPort, err2 := strconv.ParseUint(f[5], 10, 16)
+	if srcIP == nil || dstIP == nil || err1 != nil || err2 != nil {
+		return nil, nil, fmt.Errorf("proxy protocol: invalid v1 header %q", strings.TrimSpace(string(line)))
+	}
+	return &net.TCPAddr{IP: srcIP, Port: int(srcPort)},
+		&net.TCPAddr{IP: dstIP, Port: int(dstPort)}, nil
+}
+
+// readProxyV2 reads a version 2, binary, PROXY header, skipping any TLVs.
+func readProxyV2(r *bufio.Reader) (src, dst net.Addr, err error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, nil, fmt.Errorf("proxy protocol: %w", err)
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, nil, fmt.Errorf("proxy protocol: unsupported version %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, fmt.Errorf("proxy protocol: %w", err)
+	}
+
+	// A LOCAL command, or a protocol other than TCP or UDP over IPv4 or IPv6,
+	// carries no addresses to use.
+	if hdr[12]&0x0f == 0 {
+		return nil, nil, nil
+	}
+	var ipLen int
+	switch hdr[13] >> 4 {
+	case 1:
+		ipLen = net.IPv4len
+	case 2:
+		ipLen = net.IPv6len
+	default:
+		return nil, nil, nil
+	}
+	if len(body) < 2*ipLen+4 {
+		return nil, nil, errors.New("proxy protocol: v2 addresses truncated")
+	}
+	srcIP := net.IP(append([]byte(nil), body[:ipLen]...))
+	dstIP := net.IP(append([]byte(nil), body[ipLen:2*ipLen]...))
+	srcPort := int(binary.BigEndian.Uint16(body[2*ipLen:]))
+	dstPort := int(binary.BigEndian.Uint16(body[2*ipLen+2:]))
+	if hdr[13]&0x0f == 2 {
+		return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}, nil
+	}
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, nil
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ReadyChecker is an optional interface that may be implemented by a Lifecycle
+// to report whether it is ready to serve. Ready returns nil when ready, or an
+// error describing why it is not.
+type ReadyChecker interface {
+	Ready() error
+}
+
+// errQuorum is returned for a Group with ReadyQuorum and no positive Quorum,
+// which would always be ready.
+var errQuorum = errors.New("ReadyQuorum requires a positive Quorum")
+
+// ReadyPolicy determines how a Group aggregates the readiness of its
+// components.
+type ReadyPolicy int
+
+const (
+	// ReadyAll requires every enabled component to be ready.
+	ReadyAll ReadyPolicy = iota
+	// ReadyCritical requires every enabled critical component to be ready;
+	// optional components are ignored.
+	ReadyCritical
+	// ReadyQuorum requires at least the Group's Quorum of enabled components to
+	// be ready. Quorum must be positive.
+	ReadyQuorum
+)
+
+// String returns the name of the readiness policy.
+func (p ReadyPolicy) String() string {
+	switch p {
+	case ReadyAll:
+		return "all"
+	case ReadyCritical:
+		return "critical"
+	case ReadyQuorum:
+		return "quorum"
+	}
+	return fmt.Sprintf("ReadyPolicy(%d)", int(p))
+}
+
+// Ready reports whether the Group is ready according to its ReadyPolicy. A
+// component is ready when it is running and, if it implements ReadyChecker,
+// its Ready returns nil, or when it has exited cleanly. Disabled components
+// are not considered.
+func (g *Group) Ready() error {
+	g.mu.Lock()
+	components := make([]*component, 0, len(g.components))
+	for _, c := range g.components {
+		if c.initialized {
+			components = append(components, c)
+		}
+	}
+	g.mu.Unlock()
+
+	var ready int
+	var notReady []string
+	for _, c := range components {
+		if g.ReadyPolicy == ReadyCritical && c.optional {
+			continue
+		}
+		if err := c.ready(); err != nil {
+			notReady = append(notReady, fmt.Sprintf("%s: %v", c.name, err))
+			continue
+		}
+		ready++
+	}
+
+	if g.ReadyPolicy == ReadyQuorum {
+		if g.Quorum <= 0 {
+			return errQuorum
+		}
+		if ready >= g.Quorum {
+			return nil
+		}
+		return fmt.Errorf("%d of %d required components ready (%s)",
+			ready, g.Quorum, strings.Join(notReady, "; "))
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("components not ready (%s)", strings.Join(notReady, "; "))
+	}
+	return nil
+}
+
+// ready reports whether the component is ready. A component that exited
+// cleanly, having completed its work, is.
+func (c *component) ready() error {
+	state := c.loadStatus().state
+
+	if state == ComponentExited {
+		return nil
+	}
+	if state != ComponentRunning {
+		return errors.New(state.String())
+	}
+	if r, ok := c.lc.(ReadyChecker); ok {
+		return r.Ready()
+	}
+	return nil
+}
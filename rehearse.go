@@ -0,0 +1,155 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// ShutdownRehearsal describes how a lifecycle, or one component of it, would
+// behave if it were stopped now.
+type ShutdownRehearsal struct {
+	// Component names the component within a Group. It is empty for the
+	// lifecycle itself.
+	Component string
+	// Graceful reports whether the component drains in-flight work when
+	// stopped. Components implementing neither ShutdownRehearser nor
+	// DrainReporter are assumed not to.
+	Graceful bool
+	// InFlight is the number of in-flight units of work that would be
+	// drained.
+	InFlight int
+	// Projected is how long draining is projected to take at most.
+	Projected time.Duration
+	// Blockers describe what would hold up the shutdown.
+	Blockers []string
+}
+
+// ShutdownRehearser is an optional interface that may be implemented by a
+// Lifecycle to describe, without stopping or draining anything, what would
+// happen if it were stopped now. It backs the rehearse-shutdown control
+// command, which lets operators validate shutdown behaviour without downtime.
+//
+// A rehearsal is a projection only: Lifecycle has no drain step separate from
+// Stop, so nothing is drained, and the report is built from the in-flight
+// counts the lifecycle exposes.
+type ShutdownRehearser interface {
+	RehearseShutdown() []ShutdownRehearsal
+}
+
+// rehearseShutdown rehearses the shutdown of lc.
+func rehearseShutdown(lc Lifecycle) []ShutdownRehearsal {
+	switch lc := lc.(type) {
+	case ShutdownRehearser:
+		return lc.RehearseShutdown()
+	case DrainReporter:
+		return []ShutdownRehearsal{{Graceful: true, InFlight: lc.DrainProgress()}}
+	}
+	return []ShutdownRehearsal{{
+		Blockers: []string{"no graceful shutdown support"},
+	}}
+}
+
+// RehearseShutdown rehearses the shutdown of every initialized component, in
+// the order Stop would stop them.
+func (g *Group) RehearseShutdown() []ShutdownRehearsal {
+	g.mu.Lock()
+	var components []*component
+	for _, c := range g.components {
+		if c.initialized {
+			components = append(components, c)
+		}
+	}
+	g.mu.Unlock()
+
+	var rehearsals []ShutdownRehearsal
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		for _, r := range rehearseShutdown(c.lc) {
+			if r.Component == "" {
+				r.Component = c.name
+			} else {
+				r.Component = c.name + "/" + r.Component
+			}
+			rehearsals = append(rehearsals, r)
+		}
+	}
+	return rehearsals
+}
+
+// RehearseShutdown reports the requests in flight and long-lived connections
+// open, projecting the drain to take up to ShutdownTimeout while requests are
+// in flight, and up to the LongLived grace period while long-lived
+// connections are open.
+func (h *HTTPServer) RehearseShutdown() []ShutdownRehearsal {
+	timeout := h.ShutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	r := ShutdownRehearsal{Graceful: true}
+	if n := int(atomic.LoadInt64(&h.inflight)); n > 0 {
+		r.InFlight += n
+		r.Projected = timeout
+		r.Blockers = append(r.Blockers,
+			fmt.Sprintf("%d requests in flight", n))
+	}
+	if n := h.longLived.len(); n > 0 {
+		r.InFlight += n
+		if h.LongLived.Strategy != CloseImmediately {
+			grace := h.LongLived.Grace
+			if grace > timeout {
+				grace = timeout
+			}
+			if grace > r.Projected {
+				r.Projected = grace
+			}
+			r.Blockers = append(r.Blockers,
+				fmt.Sprintf("%d long-lived connections (%s)", n, h.LongLived.Strategy))
+		}
+	}
+	return []ShutdownRehearsal{r}
+}
+
+// rehearse rehearses the shutdown of the lifecycle, logging and returning a
+// table of the result.
+func (d *Dissembler) rehearse() string {
+	rehearsals := rehearseShutdown(d.lifecycle)
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tGRACEFUL\tIN-FLIGHT\tPROJECTED\tBLOCKERS")
+
+	var total time.Duration
+	for _, r := range rehearsals {
+		name := r.Component
+		if name == "" {
+			name = "-"
+		}
+		blockers := strings.Join(r.Blockers, "; ")
+		if blockers == "" {
+			blockers = "-"
+		}
+		fmt.Fprintf(w, "%s\t%t\t%d\t%s\t%s\n",
+			name, r.Graceful, r.InFlight, r.Projected, blockers)
+		total += r.Projected
+
+		msgShutdownRehearsal.emit(
+			log.String("component", r.Component),
+			log.Bool("graceful", r.Graceful),
+			log.Int("in_flight", r.InFlight),
+			log.Duration("projected", r.Projected),
+			log.String("blockers", strings.Join(r.Blockers, "; ")),
+		)
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "projected shutdown: %s\n", total)
+	return b.String()
+}
@@ -0,0 +1,66 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"math/rand"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// reloadJitter is the fraction of the interval set by WithReloadInterval by
+// which each scheduled reload is moved earlier or later at random.
+const reloadJitter = 0.1
+
+// WithReloadInterval reloads the lifecycle every interval, as SIGHUP does,
+// for configuration sourced from short-lived credentials, such as IAM roles or
+// Vault leases, so they are refreshed even when nobody sends SIGHUP. To keep a
+// fleet from reloading in step, each reload is moved up to a tenth of interval
+// earlier or later at random. Reloads are timed by the Clock set by
+// WithClock.
+func WithReloadInterval(interval time.Duration) Option {
+	return func(d *Dissembler) {
+		d.reloadInterval = interval
+	}
+}
+
+// scheduleReloads reloads the lifecycle at the interval set by
+// WithReloadInterval until the lifecycle begins stopping.
+func (d *Dissembler) scheduleReloads() {
+	if d.reloadInterval <= 0 {
+		return
+	}
+	if !canReload(d.lifecycle) {
+		msgUnableToScheduleReloads.emit(
+			log.String("error", "lifecycle does not implement Reloader"),
+		)
+		return
+	}
+
+	d.spawn("reload-interval", func(ctx context.Context) error {
+		clock := clockOr(d.clock)
+		for {
+			t := clock.NewTimer(jitter(d.reloadInterval, reloadJitter))
+			select {
+			case <-t.C():
+			case <-ctx.Done():
+				t.Stop()
+				return nil
+			}
+			msgScheduledReload.emit(
+				log.Duration("interval", d.reloadInterval),
+			)
+			d.request(context.Background(), syscall.SIGHUP)
+		}
+	})
+}
+
+// jitter returns d moved earlier or later at random by up to the fraction f
+// of it.
+func jitter(d time.Duration, f float64) time.Duration {
+	return d + time.Duration((rand.Float64()*2-1)*f*float64(d))
+}
@@ -0,0 +1,124 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// Requirements are the minimum host resources a daemon needs to serve.
+type Requirements struct {
+	// MinMemory is the minimum total memory, in bytes.
+	MinMemory int64
+	// MinCPUs is the minimum number of CPUs usable by the process, taking CPU
+	// affinity and cgroup quotas into account.
+	MinCPUs int
+	// MinDiskFree is the minimum free space, in bytes, on the filesystem
+	// holding each path, such as data and log directories.
+	MinDiskFree map[string]int64
+}
+
+// RequirementsError is returned by Serve when the host does not meet the
+// Requirements given to WithRequirements.
+type RequirementsError struct {
+	// Unmet describes each requirement not met.
+	Unmet []string
+}
+
+// Error reports every requirement not met.
+func (e *RequirementsError) Error() string {
+	return "host does not meet requirements: " + strings.Join(e.Unmet, "; ")
+}
+
+// WithRequirements checks, before Init, that the host meets r, failing Serve
+// with a RequirementsError listing every requirement not met, so a daemon
+// fails fast on a host that cannot serve rather than failing under load.
+// Requirements that cannot be measured on the platform, such as memory
+// outside Linux, are skipped with a warning.
+func WithRequirements(r Requirements) Option {
+	return func(d *Dissembler) {
+		d.checks = append(d.checks, preflightCheck{
+			name: "requirements",
+			fn:   r.check,
+		})
+	}
+}
+
+// check reports the requirements not met.
+func (r Requirements) check() error {
+	var unmet []string
+	skip := func(what string, err error) {
+		msgUnableToCheckRequirement.emit(
+			log.String("requirement", what),
+			log.String("error", err.Error()),
+		)
+	}
+
+	if r.MinMemory > 0 {
+		mem, err := hostMemory()
+		switch {
+		case err != nil:
+			skip("memory", err)
+		case mem < r.MinMemory:
+			unmet = append(unmet, fmt.Sprintf("memory %s, need %s",
+				formatBytes(mem), formatBytes(r.MinMemory)))
+		}
+	}
+
+	if r.MinCPUs > 0 {
+		cpus := runtime.NumCPU()
+		quota, ok, err := cpuQuota()
+		switch {
+		case err != nil:
+			skip("cpus", err)
+		case ok && int(math.Ceil(quota)) < cpus:
+			cpus = int(math.Ceil(quota))
+		}
+		if cpus < r.MinCPUs {
+			unmet = append(unmet, fmt.Sprintf("%d CPUs, need %d", cpus, r.MinCPUs))
+		}
+	}
+
+	paths := make([]string, 0, len(r.MinDiskFree))
+	for path := range r.MinDiskFree {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		free, err := diskFree(path)
+		if err != nil {
+			unmet = append(unmet, fmt.Sprintf("disk %s: %v", path, err))
+			continue
+		}
+		if need := r.MinDiskFree[path]; free < need {
+			unmet = append(unmet, fmt.Sprintf("disk %s has %s free, need %s",
+				path, formatBytes(free), formatBytes(need)))
+		}
+	}
+
+	if len(unmet) > 0 {
+		return &RequirementsError{Unmet: unmet}
+	}
+	return nil
+}
+
+// formatBytes formats n bytes in binary units, such as 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,14 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import "errors"
+
+// hostMemory is not supported outside Linux.
+func hostMemory() (int64, error) {
+	return 0, errors.New("host memory is only measured on Linux")
+}
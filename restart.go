@@ -0,0 +1,97 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartPolicy determines whether a Group restarts a component whose Start
+// has returned.
+type RestartPolicy int
+
+const (
+	// RestartNever never restarts a component. A failed component's error is
+	// returned from the Group's Start, taking the process down.
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts a component whose Start returned an error.
+	RestartOnFailure
+	// RestartAlways restarts a component whenever its Start returns.
+	RestartAlways
+)
+
+// String returns the name of the restart policy.
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	}
+	return fmt.Sprintf("RestartPolicy(%d)", int(p))
+}
+
+// MinRestartDelay is the shortest delay between restarts of a component, and
+// between retries of a degraded optional component, so one whose Start
+// returns at once does not spin.
+const MinRestartDelay = 100 * time.Millisecond
+
+// Backoff is the exponentially increasing delay between restarts of a
+// component.
+type Backoff struct {
+	// Initial is the delay before the first restart. It is at least
+	// MinRestartDelay.
+	Initial time.Duration
+	// Max caps the delay between restarts. If zero, the delay keeps doubling.
+	Max time.Duration
+	// Attempts is the number of consecutive failed restarts after which the
+	// component's error is returned from the Group's Start. If zero, the
+	// component is restarted indefinitely. A run lasting longer than Max, or
+	// than the delay preceding it if Max is zero, ends the sequence, and the
+	// delay returns to Initial.
+	Attempts int
+}
+
+// delay returns the wait before restart attempt n, counted from zero.
+func (b Backoff) delay(n int) time.Duration {
+	d := b.Initial
+	if d < MinRestartDelay {
+		d = MinRestartDelay
+	}
+	for i := 0; i < n; i++ {
+		if b.Max > 0 && d >= b.Max {
+			break
+		}
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if d < MinRestartDelay {
+		d = MinRestartDelay
+	}
+	return d
+}
+
+// stable reports whether a run lasting elapsed, after n consecutive failures,
+// was long enough for a failure ending it to begin a new sequence.
+func (b Backoff) stable(n int, elapsed time.Duration) bool {
+	window := b.Max
+	if window == 0 {
+		window = b.delay(n - 1)
+	}
+	return elapsed > window
+}
+
+// Restart declares a component's restart policy and the backoff applied
+// between restarts. Components without a restart policy are never restarted.
+func Restart(policy RestartPolicy, backoff Backoff) ComponentOption {
+	return func(c *component) {
+		c.restart = policy
+		c.backoff = backoff
+	}
+}
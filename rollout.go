@@ -0,0 +1,106 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// bakePoll is how often health checks run while a reload bakes.
+const bakePoll = time.Second
+
+// ErrReloadDegraded is the outcome of a reload rolled back because health
+// checks failed while it baked.
+var ErrReloadDegraded = errors.New("reloaded configuration failed health checks")
+
+// Rollbacker is an optional interface that may be implemented by a Lifecycle
+// supporting reloads to restore the configuration it applied before the most
+// recent reload. It backs WithReloadBake.
+type Rollbacker interface {
+	Rollback(ctx context.Context) error
+}
+
+// HealthCheck reports whether the lifecycle is healthy, returning nil when it
+// is or an error describing why it is not.
+type HealthCheck func(ctx context.Context) error
+
+// WithReloadBake canaries configuration changes: once a reload has applied a
+// new configuration, it bakes for bake, running checks, and the lifecycle's
+// Ready if it implements ReadyChecker, every second. Should any fail, the
+// lifecycle is rolled back to the previous configuration with Rollback, and
+// the reload fails with ErrReloadDegraded, keeping the configuration
+// generation. Lifecycles not implementing Rollbacker are not baked.
+//
+// The reload, and with it the handling of other signals, lasts until the bake
+// ends, so bake should be short.
+func WithReloadBake(bake time.Duration, checks ...HealthCheck) Option {
+	return func(d *Dissembler) {
+		d.bake = bake
+		d.healthChecks = append(d.healthChecks, checks...)
+	}
+}
+
+// bakeReload runs the health checks set by WithReloadBake until the bake
+// ends, rolling the lifecycle back if any fails.
+func (d *Dissembler) bakeReload(ctx context.Context) error {
+	if d.bake <= 0 {
+		return nil
+	}
+	rb, ok := d.lifecycle.(Rollbacker)
+	if !ok {
+		msgUnableToBakeReload.emit(
+			log.String("error", "lifecycle does not implement Rollbacker"),
+		)
+		return nil
+	}
+	checks := d.healthChecks
+	if rc, ok := d.lifecycle.(ReadyChecker); ok {
+		checks = append(checks[:len(checks):len(checks)], func(context.Context) error {
+			return rc.Ready()
+		})
+	}
+
+	msgBakingReload.emit(
+		log.Duration("bake", d.bake),
+	)
+	clock := clockOr(d.clock)
+	deadline := clock.NewTimer(d.bake)
+	defer deadline.Stop()
+	t := clock.NewTicker(bakePoll)
+	defer t.Stop()
+	for {
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				return d.rollback(ctx, rb, err)
+			}
+		}
+		select {
+		case <-t.C():
+		case <-deadline.C():
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// rollback rolls the lifecycle back after a health check failed with cause.
+func (d *Dissembler) rollback(ctx context.Context, rb Rollbacker, cause error) error {
+	msgRollingBackReload.emit(
+		log.String("error", cause.Error()),
+	)
+	err := fmt.Errorf("%w: %v", ErrReloadDegraded, cause)
+	if rerr := rb.Rollback(ctx); rerr != nil {
+		msgUnableToRollBack.emit(
+			log.String("error", rerr.Error()),
+		)
+		return fmt.Errorf("%w; rolling back: %v", err, rerr)
+	}
+	return err
+}
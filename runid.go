@@ -0,0 +1,41 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"crypto/rand"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+)
+
+// runID is the ID of the current run, as a string.
+var runID atomic.Value
+
+// RunID returns the ID of the current run: a random UUID generated each time
+// Serve is called, including in a process started by an upgrade. It is
+// attached to every entry Dissembler logs, every event published and the
+// exported metrics, so timelines spanning several restarts can be stitched
+// together. It is empty before Serve is first called.
+func RunID() string {
+	id, _ := runID.Load().(string)
+	return id
+}
+
+// startRun generates the ID of a new run and exports it.
+func startRun() {
+	runID.Store(newUUID())
+	Metrics.Set(metricRunID, expvar.Func(func() interface{} {
+		return RunID()
+	}))
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
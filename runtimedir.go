@@ -0,0 +1,109 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// runtimeDirMode is the mode of a runtime directory created by Dissembler.
+const runtimeDirMode = 0750
+
+// envRuntimeDir names the runtime directory an upgraded process takes over,
+// and removes on exit, from its predecessor.
+const envRuntimeDir = "DISSEMBLER_RUNTIME_DIR"
+
+// WithRuntimeDir keeps a runtime directory at path for the sockets, pid and
+// state files of the process, created before Init with mode 0750 and removed
+// once the lifecycle has stopped. A process exiting after an upgrade leaves
+// the directory to its successor, which removes it in turn. A relative
+// control socket path given to WithControlSocket is resolved within it.
+//
+// Under systemd with RuntimeDirectory= set, the directory named by the
+// RUNTIME_DIRECTORY environment variable is used instead of path, and its
+// creation and removal are left to systemd. A directory that already exists
+// at path has its mode corrected but is not removed.
+func WithRuntimeDir(path string) Option {
+	return func(d *Dissembler) {
+		d.runtimeDir = path
+	}
+}
+
+// RuntimeDir returns the runtime directory set up by WithRuntimeDir, or an
+// empty string if there is none.
+func (d *Dissembler) RuntimeDir() string {
+	return d.runtimeDir
+}
+
+// setupRuntimeDir creates the runtime directory, if any, returning a function
+// removing it if it was created or handed over by an upgrade, unless the
+// process exits having handed it over in turn.
+func (d *Dissembler) setupRuntimeDir() (remove func(), err error) {
+	remove = func() {}
+	if d.runtimeDir == "" {
+		return remove, nil
+	}
+	inherited := os.Getenv(envRuntimeDir)
+	os.Unsetenv(envRuntimeDir)
+	if dirs := os.Getenv("RUNTIME_DIRECTORY"); dirs != "" {
+		// systemd lists one directory per RuntimeDirectory= entry.
+		d.runtimeDir = strings.Split(dirs, ":")[0]
+		d.resolveRuntimePaths()
+		return remove, nil
+	}
+
+	fi, err := os.Stat(d.runtimeDir)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(d.runtimeDir, runtimeDirMode); err != nil {
+			return remove, err
+		}
+		// MkdirAll is subject to the umask.
+		if err := os.Chmod(d.runtimeDir, runtimeDirMode); err != nil {
+			return remove, err
+		}
+		d.ownsRuntimeDir = true
+	case err != nil:
+		return remove, err
+	case !fi.IsDir():
+		return remove, fmt.Errorf("runtime directory %s: not a directory", d.runtimeDir)
+	default:
+		if fi.Mode().Perm() != runtimeDirMode {
+			if err := os.Chmod(d.runtimeDir, runtimeDirMode); err != nil {
+				return remove, err
+			}
+		}
+		d.ownsRuntimeDir = inherited == d.runtimeDir
+	}
+	d.resolveRuntimePaths()
+
+	if !d.ownsRuntimeDir {
+		return remove, nil
+	}
+	dir := d.runtimeDir
+	return func() {
+		if d.exitReason == ExitUpgraded {
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			msgUnableToRemoveRuntimeDir.emit(
+				log.String("path", dir),
+				log.String("error", err.Error()),
+			)
+		}
+	}, nil
+}
+
+// resolveRuntimePaths resolves relative paths of files kept in the runtime
+// directory.
+func (d *Dissembler) resolveRuntimePaths() {
+	if d.controlPath != "" && !filepath.IsAbs(d.controlPath) {
+		d.controlPath = filepath.Join(d.runtimeDir, d.controlPath)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"strings"
+
+	log "github.com/uber-go/zap"
+)
+
+// Namespace is a Linux namespace the process may leave with WithUnshare.
+type Namespace int
+
+const (
+	// NamespaceMount is the mount namespace. The process's mounts are made
+	// private, so mounts it makes are not seen by the host and the reverse.
+	NamespaceMount Namespace = 1 << iota
+	// NamespaceIPC is the System V IPC and POSIX message queue namespace.
+	NamespaceIPC
+)
+
+// String returns the names of the namespaces.
+func (ns Namespace) String() string {
+	var names []string
+	if ns&NamespaceMount != 0 {
+		names = append(names, "mount")
+	}
+	if ns&NamespaceIPC != 0 {
+		names = append(names, "ipc")
+	}
+	return strings.Join(names, ",")
+}
+
+// WithChroot changes the root directory of the process to dir once Init has
+// returned, so Init may still open files, bind privileged ports and read
+// configuration outside it. Paths used afterwards, including by Dissembler,
+// such as the runtime directory removed when Serve returns, are resolved within
+// dir. It gives simple daemons a cheap containment layer
+// without a container runtime, but requires privileges and is not a security
+// boundary against a process that keeps them.
+func WithChroot(dir string) Option {
+	return func(d *Dissembler) {
+		d.chroot = dir
+	}
+}
+
+// WithUnshare moves the process into new namespaces once Init has returned,
+// before any chroot given with WithChroot. It is supported on Linux for
+// binaries built without cgo, such as with CGO_ENABLED=0, since every thread
+// of the process must unshare; otherwise Serve fails.
+func WithUnshare(ns ...Namespace) Option {
+	return func(d *Dissembler) {
+		for _, n := range ns {
+			d.unshare |= n
+		}
+	}
+}
+
+// sandbox applies WithUnshare and WithChroot.
+func (d *Dissembler) sandbox() error {
+	if d.unshare == 0 && d.chroot == "" {
+		return nil
+	}
+	if err := sandbox(d.chroot, d.unshare); err != nil {
+		return err
+	}
+	msgSandboxed.emit(
+		log.String("chroot", d.chroot),
+		log.String("unshared", d.unshare.String()),
+	)
+	return nil
+}
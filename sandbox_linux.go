@@ -0,0 +1,61 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// sandbox unshares ns and changes the root directory to dir, if set.
+//
+// Namespaces, and with them the root directory once the mount namespace is
+// unshared, belong to each thread, so the calls are made on every thread of
+// the process. Threads created later inherit them from their creator.
+func sandbox(dir string, ns Namespace) error {
+	if ns == 0 {
+		if err := syscall.Chroot(dir); err != nil {
+			return os.NewSyscallError("chroot", err)
+		}
+		return os.Chdir("/")
+	}
+
+	var flags uintptr
+	if ns&NamespaceMount != 0 {
+		flags |= syscall.CLONE_NEWNS
+	}
+	if ns&NamespaceIPC != 0 {
+		flags |= syscall.CLONE_NEWIPC
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_UNSHARE, flags, 0, 0); errno != 0 {
+		return allThreadsError("unshare", errno)
+	}
+
+	if ns&NamespaceMount != 0 {
+		root, _ := syscall.BytePtrFromString("/")
+		if _, _, errno := syscall.AllThreadsSyscall6(syscall.SYS_MOUNT, 0,
+			uintptr(unsafe.Pointer(root)), 0, syscall.MS_REC|syscall.MS_PRIVATE, 0, 0); errno != 0 {
+			return allThreadsError("mount", errno)
+		}
+	}
+
+	if dir == "" {
+		return nil
+	}
+	path, err := syscall.BytePtrFromString(dir)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CHROOT,
+		uintptr(unsafe.Pointer(path)), 0, 0); errno != 0 {
+		return allThreadsError("chroot", errno)
+	}
+	root, _ := syscall.BytePtrFromString("/")
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CHDIR,
+		uintptr(unsafe.Pointer(root)), 0, 0); errno != 0 {
+		return allThreadsError("chdir", errno)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// sandbox changes the root directory to dir, if set. Namespaces exist only on
+// Linux.
+func sandbox(dir string, ns Namespace) error {
+	if ns != 0 {
+		return errors.New("unshare " + ns.String() + ": namespaces require Linux")
+	}
+	if err := syscall.Chroot(dir); err != nil {
+		return os.NewSyscallError("chroot", err)
+	}
+	return os.Chdir("/")
+}
@@ -0,0 +1,175 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// DefaultDrainLead is how long before a scheduled stop the lifecycle starts
+// draining, if the control command stop --at is given no --lead.
+const DefaultDrainLead = 5 * time.Minute
+
+// stopSchedule is a graceful stop scheduled for a wall-clock time.
+type stopSchedule struct {
+	mu     sync.Mutex
+	at     time.Time
+	cancel chan struct{}
+}
+
+// WithDrainUntil schedules a graceful stop for the time returned by until,
+// for planned maintenance of services that must drain slowly. From lead before
+// that time the lifecycle is quiesced, if it implements Quiescer, so it stops
+// taking on new work and fails readiness while finishing what it has; at that
+// time it is stopped as by SIGTERM.
+//
+// until is called once the lifecycle is ready and again after every reload,
+// and may return the zero time to cancel the schedule. A stop may also be
+// scheduled through the control socket:
+//
+//	stop --at 02:00 [--lead 10m]   stop at the next 02:00 local time, or at an
+//	                               RFC 3339 time, draining from lead before;
+//	                               lead defaults to DefaultDrainLead
+//	stop --cancel                  cancel the scheduled stop
+func WithDrainUntil(until func() time.Time, lead time.Duration) Option {
+	return func(d *Dissembler) {
+		d.drainUntil = until
+		d.drainLead = lead
+	}
+}
+
+// ScheduledStop returns the time the lifecycle is scheduled to stop, or the
+// zero time if no stop is scheduled.
+func (d *Dissembler) ScheduledStop() time.Time {
+	d.schedule.mu.Lock()
+	defer d.schedule.mu.Unlock()
+	return d.schedule.at
+}
+
+// loadDrainUntil schedules the stop set by WithDrainUntil.
+func (d *Dissembler) loadDrainUntil() {
+	if d.drainUntil != nil {
+		d.scheduleStop(d.drainUntil(), d.drainLead)
+	}
+}
+
+// scheduleStop schedules a graceful stop at at, quiescing the lifecycle from
+// lead before, replacing any stop already scheduled. A zero at cancels the
+// schedule, resuming the lifecycle if it was quiesced for it.
+func (d *Dissembler) scheduleStop(at time.Time, lead time.Duration) {
+	s := &d.schedule
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.at.Equal(at) {
+		return
+	}
+	if s.cancel != nil {
+		close(s.cancel)
+		s.cancel = nil
+	}
+	s.at = at
+	if at.IsZero() {
+		msgStopUnscheduled.emit()
+		return
+	}
+
+	msgStopScheduled.emit(
+		log.String("at", at.Format(time.RFC3339)),
+		log.Duration("lead", lead),
+	)
+	cancel := make(chan struct{})
+	s.cancel = cancel
+	d.spawn("scheduled-stop", func(ctx context.Context) error {
+		d.runSchedule(ctx, at, lead, cancel)
+		return nil
+	})
+}
+
+// runSchedule drains and then stops the lifecycle at at, unless cancelled or
+// the lifecycle begins stopping first.
+func (d *Dissembler) runSchedule(stopping context.Context, at time.Time, lead time.Duration, cancel <-chan struct{}) {
+	clock := clockOr(d.clock)
+	ctx := context.Background()
+	wait := func(until time.Time) bool {
+		t := clock.NewTimer(until.Sub(clock.Now()))
+		defer t.Stop()
+		select {
+		case <-t.C():
+			return true
+		case <-cancel:
+			return false
+		case <-stopping.Done():
+			return false
+		}
+	}
+
+	if !wait(at.Add(-lead)) {
+		return
+	}
+	msgDrainingForStop.emit(
+		log.String("at", at.Format(time.RFC3339)),
+	)
+	if err := d.quiesce(ctx); err != nil && !errors.Is(err, ErrQuiesceUnsupported) {
+		msgUnableToQuiesce.emit(
+			log.String("error", err.Error()),
+		)
+	}
+
+	if !wait(at) {
+		if err := d.resume(ctx); err != nil {
+			msgUnableToResume.emit(
+				log.String("error", err.Error()),
+			)
+		}
+		return
+	}
+	d.request(ctx, syscall.SIGTERM)
+}
+
+// controlStop schedules or cancels a stop as requested by the stop control
+// command's --at, --lead and --cancel flags, returning the outcome.
+func (d *Dissembler) controlStop(flags map[string]string) (string, error) {
+	if _, ok := flags["cancel"]; ok {
+		d.scheduleStop(time.Time{}, 0)
+		return "stop cancelled", nil
+	}
+
+	lead := DefaultDrainLead
+	if s, ok := flags["lead"]; ok {
+		var err error
+		if lead, err = time.ParseDuration(s); err != nil || lead < 0 {
+			return "", fmt.Errorf("invalid --lead %q", s)
+		}
+	}
+	at, err := parseStopAt(flags["at"], clockOr(d.clock).Now().In(Location()))
+	if err != nil {
+		return "", err
+	}
+	d.scheduleStop(at, lead)
+	return "stopping at " + at.Format(time.RFC3339), nil
+}
+
+// parseStopAt parses the time of a scheduled stop: an RFC 3339 time, or a
+// local time of day such as "02:00", meaning its next occurrence after now.
+func parseStopAt(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("15:04", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at %q: want HH:MM or an RFC 3339 time", s)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
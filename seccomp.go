@@ -0,0 +1,88 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+
+	log "github.com/uber-go/zap"
+)
+
+// SeccompAction is what a seccomp filter does with a syscall not allowed.
+type SeccompAction int
+
+const (
+	// SeccompErrno fails the syscall with EPERM, so code probing for newer
+	// syscalls falls back instead of crashing.
+	SeccompErrno SeccompAction = iota
+	// SeccompKill kills the process.
+	SeccompKill
+	// SeccompLog allows the syscall but logs it to the kernel audit log, for
+	// building a profile before enforcing it.
+	SeccompLog
+)
+
+// String returns the name of the action.
+func (a SeccompAction) String() string {
+	switch a {
+	case SeccompErrno:
+		return "errno"
+	case SeccompKill:
+		return "kill"
+	case SeccompLog:
+		return "log"
+	}
+	return fmt.Sprintf("SeccompAction(%d)", int(a))
+}
+
+// SeccompProfile is a seccomp-bpf syscall allowlist.
+type SeccompProfile struct {
+	// Allow lists the numbers of the syscalls allowed, such as
+	// syscall.SYS_READ, for the architecture the binary is built for.
+	Allow []uintptr
+	// Action is taken for any other syscall.
+	Action SeccompAction
+}
+
+// DefaultSeccompProfile returns a profile allowing the syscalls used by the
+// Go runtime and the standard library for a typical network daemon: files,
+// sockets, polling, timers, signals, threads and re-executing on upgrade.
+// Lifecycles may append to Allow whatever else they need. Only amd64 and
+// arm64 are supported; elsewhere Allow is empty.
+func DefaultSeccompProfile() SeccompProfile {
+	return SeccompProfile{Allow: append([]uintptr(nil), defaultSeccompAllow...)}
+}
+
+// WithSeccomp installs a seccomp-bpf filter allowing only the syscalls in p
+// once the lifecycle is ready, so Init and Start may still do whatever setup
+// they need, limiting what an attacker who compromises an internet-facing
+// service can do. The filter applies to every thread, cannot be removed, and
+// is inherited across upgrades. It also sets no_new_privs. If it cannot be
+// installed, the lifecycle is stopped and Serve fails.
+//
+// Seccomp filters are supported on Linux for amd64 and arm64.
+func WithSeccomp(p SeccompProfile) Option {
+	return func(d *Dissembler) {
+		d.seccomp = &p
+	}
+}
+
+// applySeccomp installs the seccomp filter, if any, failing the lifecycle if
+// it cannot be installed.
+func (d *Dissembler) applySeccomp() {
+	if d.seccomp == nil {
+		return
+	}
+	if err := installSeccomp(*d.seccomp); err != nil {
+		select {
+		case d.errc <- fmt.Errorf("installing seccomp filter: %w", err):
+		default:
+		}
+		return
+	}
+	msgSeccompFilterInstalled.emit(
+		log.Int("syscalls", len(d.seccomp.Allow)),
+		log.String("action", d.seccomp.Action.String()),
+	)
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package dissembler
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	seccompSetModeFilter     = 1
+	seccompFilterFlagTSync   = 1
+	seccompRetKillProcess    = 0x80000000
+	seccompRetErrno          = 0x00050000
+	seccompRetLog            = 0x7ffc0000
+	seccompRetAllow          = 0x7fff0000
+	seccompDataNr            = 0
+	seccompDataArch          = 4
+	maxSeccompInstructions   = 4096
+	seccompX32SyscallBit     = 0x40000000
+	seccompFixedInstructions = 7
+)
+
+// installSeccomp installs a filter allowing the syscalls in p on every thread.
+func installSeccomp(p SeccompProfile) error {
+	if len(p.Allow)*2+seccompFixedInstructions > maxSeccompInstructions {
+		return errors.New("too many syscalls allowed")
+	}
+	filter := seccompProgram(p)
+	prog := syscall.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// no_new_privs is set on the calling thread, and the kernel sets it on
+	// the others as it synchronizes the filter to them.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return os.NewSyscallError("prctl", errno)
+	}
+	tid, _, errno := syscall.RawSyscall(sysSeccomp, seccompSetModeFilter,
+		seccompFilterFlagTSync, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	if errno != 0 {
+		return os.NewSyscallError("seccomp", errno)
+	}
+	if tid != 0 {
+		return errors.New("seccomp: unable to synchronize filter to all threads")
+	}
+	return nil
+}
+
+// seccompProgram compiles p to BPF. Syscalls of other architectures, and on
+// amd64 of the x32 ABI, are refused outright since their numbers differ.
+func seccompProgram(p SeccompProfile) []syscall.SockFilter {
+	stmt := func(code uint16, k uint32) syscall.SockFilter {
+		return syscall.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) syscall.SockFilter {
+		return syscall.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	action := uint32(seccompRetErrno | uint32(syscall.EPERM))
+	switch p.Action {
+	case SeccompKill:
+		action = seccompRetKillProcess
+	case SeccompLog:
+		action = seccompRetLog
+	}
+
+	filter := []syscall.SockFilter{
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataArch),
+		jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, auditArch, 1, 0),
+		stmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataNr),
+		jump(syscall.BPF_JMP|syscall.BPF_JGE|syscall.BPF_K, seccompX32SyscallBit, 0, 1),
+		stmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+	}
+	for _, nr := range p.Allow {
+		filter = append(filter,
+			jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, uint32(nr), 0, 1),
+			stmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow),
+		)
+	}
+	return append(filter, stmt(syscall.BPF_RET|syscall.BPF_K, action))
+}
+
+// defaultSeccompAllow are the syscalls allowed by DefaultSeccompProfile on
+// both amd64 and arm64, followed by those of the architecture built for.
+var defaultSeccompAllow = append([]uintptr{
+	syscall.SYS_READ, syscall.SYS_WRITE, syscall.SYS_READV, syscall.SYS_WRITEV,
+	syscall.SYS_PREAD64, syscall.SYS_PWRITE64, syscall.SYS_CLOSE,
+	syscall.SYS_FSTAT, syscall.SYS_LSEEK, syscall.SYS_IOCTL, syscall.SYS_FCNTL,
+	syscall.SYS_FLOCK, syscall.SYS_FSYNC, syscall.SYS_FDATASYNC,
+	syscall.SYS_TRUNCATE, syscall.SYS_FTRUNCATE, syscall.SYS_GETDENTS64,
+	syscall.SYS_GETCWD, syscall.SYS_CHDIR, syscall.SYS_FCHDIR,
+	syscall.SYS_FCHMOD, syscall.SYS_FCHOWN, syscall.SYS_UMASK,
+	syscall.SYS_OPENAT, syscall.SYS_READLINKAT, syscall.SYS_UNLINKAT,
+	syscall.SYS_RENAMEAT, syscall.SYS_MKDIRAT, syscall.SYS_FCHMODAT,
+	syscall.SYS_FCHOWNAT, syscall.SYS_FACCESSAT, syscall.SYS_LINKAT,
+	syscall.SYS_SYMLINKAT, syscall.SYS_UTIMENSAT, syscall.SYS_STATFS,
+	syscall.SYS_FSTATFS, syscall.SYS_FADVISE64, syscall.SYS_SYNC_FILE_RANGE,
+	syscall.SYS_SENDFILE, syscall.SYS_SPLICE, syscall.SYS_TEE,
+	syscall.SYS_DUP, syscall.SYS_DUP3, syscall.SYS_PIPE2,
+
+	syscall.SYS_SOCKET, syscall.SYS_SOCKETPAIR, syscall.SYS_CONNECT,
+	syscall.SYS_ACCEPT, syscall.SYS_ACCEPT4, syscall.SYS_BIND, syscall.SYS_LISTEN,
+	syscall.SYS_SHUTDOWN, syscall.SYS_SENDTO, syscall.SYS_RECVFROM,
+	syscall.SYS_SENDMSG, syscall.SYS_RECVMSG, syscall.SYS_GETSOCKNAME,
+	syscall.SYS_GETPEERNAME, syscall.SYS_SETSOCKOPT, syscall.SYS_GETSOCKOPT,
+
+	syscall.SYS_EPOLL_CREATE1, syscall.SYS_EPOLL_CTL, syscall.SYS_EPOLL_PWAIT,
+	syscall.SYS_PSELECT6, syscall.SYS_PPOLL, syscall.SYS_EVENTFD2,
+	syscall.SYS_TIMERFD_CREATE, syscall.SYS_TIMERFD_SETTIME,
+	syscall.SYS_TIMERFD_GETTIME,
+
+	syscall.SYS_MMAP, syscall.SYS_MPROTECT, syscall.SYS_MUNMAP,
+	syscall.SYS_MREMAP, syscall.SYS_MADVISE, syscall.SYS_MINCORE,
+	syscall.SYS_MSYNC, syscall.SYS_BRK,
+
+	syscall.SYS_CLONE, syscall.SYS_EXECVE, syscall.SYS_EXIT,
+	syscall.SYS_EXIT_GROUP, syscall.SYS_WAIT4, syscall.SYS_WAITID,
+	syscall.SYS_KILL, syscall.SYS_TKILL, syscall.SYS_TGKILL,
+	syscall.SYS_FUTEX, syscall.SYS_SET_ROBUST_LIST, syscall.SYS_GET_ROBUST_LIST,
+	syscall.SYS_SET_TID_ADDRESS, syscall.SYS_SCHED_YIELD,
+	syscall.SYS_SCHED_GETAFFINITY, syscall.SYS_SCHED_SETAFFINITY,
+	syscall.SYS_GETPRIORITY, syscall.SYS_SETPRIORITY, syscall.SYS_PRCTL,
+	syscall.SYS_CAPGET,
+
+	syscall.SYS_RT_SIGACTION, syscall.SYS_RT_SIGPROCMASK,
+	syscall.SYS_RT_SIGRETURN, syscall.SYS_RT_SIGTIMEDWAIT,
+	syscall.SYS_RT_SIGPENDING, syscall.SYS_RT_SIGSUSPEND,
+	syscall.SYS_SIGALTSTACK, syscall.SYS_RESTART_SYSCALL,
+
+	syscall.SYS_NANOSLEEP, syscall.SYS_CLOCK_GETTIME, syscall.SYS_CLOCK_GETRES,
+	syscall.SYS_CLOCK_NANOSLEEP, syscall.SYS_GETTIMEOFDAY,
+	syscall.SYS_SETITIMER, syscall.SYS_GETITIMER, syscall.SYS_TIMER_CREATE,
+	syscall.SYS_TIMER_SETTIME, syscall.SYS_TIMER_DELETE,
+
+	syscall.SYS_GETPID, syscall.SYS_GETPPID, syscall.SYS_GETTID,
+	syscall.SYS_GETPGID, syscall.SYS_GETSID, syscall.SYS_GETUID,
+	syscall.SYS_GETEUID, syscall.SYS_GETGID, syscall.SYS_GETEGID,
+	syscall.SYS_GETRESUID, syscall.SYS_GETRESGID, syscall.SYS_GETGROUPS,
+	syscall.SYS_UNAME, syscall.SYS_SYSINFO, syscall.SYS_GETRUSAGE,
+	syscall.SYS_GETRLIMIT, syscall.SYS_SETRLIMIT, syscall.SYS_PRLIMIT64,
+}, archSeccompAllow...)
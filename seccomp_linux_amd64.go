@@ -0,0 +1,39 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import "syscall"
+
+const (
+	// auditArch is AUDIT_ARCH_X86_64.
+	auditArch = 0xc000003e
+	// sysSeccomp is the seccomp syscall, which the syscall package lacks.
+	sysSeccomp = 317
+)
+
+// archSeccompAllow are the amd64 syscalls allowed by DefaultSeccompProfile,
+// including those newer than the syscall package.
+var archSeccompAllow = []uintptr{
+	syscall.SYS_OPEN, syscall.SYS_STAT, syscall.SYS_LSTAT,
+	syscall.SYS_NEWFSTATAT, syscall.SYS_ACCESS, syscall.SYS_READLINK,
+	syscall.SYS_UNLINK, syscall.SYS_RENAME, syscall.SYS_MKDIR, syscall.SYS_RMDIR,
+	syscall.SYS_CHMOD, syscall.SYS_GETDENTS, syscall.SYS_PIPE, syscall.SYS_DUP2,
+	syscall.SYS_POLL, syscall.SYS_SELECT, syscall.SYS_EPOLL_CREATE,
+	syscall.SYS_EPOLL_WAIT, syscall.SYS_ARCH_PRCTL, syscall.SYS_TIME,
+	syscall.SYS_GETPGRP,
+	299, // recvmmsg
+	307, // sendmmsg
+	316, // renameat2
+	318, // getrandom
+	324, // membarrier
+	326, // copy_file_range
+	332, // statx
+	334, // rseq
+	424, // pidfd_send_signal
+	434, // pidfd_open
+	435, // clone3
+	436, // close_range
+	439, // faccessat2
+	441, // epoll_pwait2
+}
@@ -0,0 +1,31 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import "syscall"
+
+const (
+	// auditArch is AUDIT_ARCH_AARCH64.
+	auditArch = 0xc00000b7
+	// sysSeccomp is the seccomp syscall.
+	sysSeccomp = syscall.SYS_SECCOMP
+)
+
+// archSeccompAllow are the arm64 syscalls allowed by DefaultSeccompProfile,
+// including those newer than the syscall package.
+var archSeccompAllow = []uintptr{
+	syscall.SYS_FSTATAT, syscall.SYS_GETRANDOM, syscall.SYS_SENDMMSG,
+	syscall.SYS_RECVMMSG,
+	276, // renameat2
+	283, // membarrier
+	285, // copy_file_range
+	291, // statx
+	293, // rseq
+	424, // pidfd_send_signal
+	434, // pidfd_open
+	435, // clone3
+	436, // close_range
+	439, // faccessat2
+	441, // epoll_pwait2
+}
@@ -0,0 +1,17 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package dissembler
+
+import "errors"
+
+// defaultSeccompAllow is empty where seccomp filters are not supported.
+var defaultSeccompAllow []uintptr
+
+// installSeccomp is not supported outside Linux on amd64 and arm64.
+func installSeccomp(p SeccompProfile) error {
+	return errors.New("seccomp filters require Linux on amd64 or arm64")
+}
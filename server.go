@@ -0,0 +1,65 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Server returns a Lifecycle running any server with a blocking serve function
+// and a shutdown function, such as a server's Serve and Shutdown or Close
+// methods. Start calls serve, and Stop calls shutdown with a context expiring
+// after DefaultShutdownTimeout, or earlier when the stop budget set by
+// WithStopBudget is spent. The error serve returns once shutdown has been
+// called, such as http.ErrServerClosed or net.ErrClosed, is discarded.
+//
+// Listeners are bound by the caller, through Listen so they survive upgrades.
+// For instance, to run a net/rpc server:
+//
+//	ln, err := dissembler.Listen("tcp", ":1234")
+//	...
+//	lc := dissembler.Server(
+//		func() error { rpc.Accept(ln); return nil },
+//		func(context.Context) error { return ln.Close() },
+//	)
+func Server(serve func() error, shutdown func(ctx context.Context) error) Lifecycle {
+	return &server{serve: serve, shutdown: shutdown}
+}
+
+// server adapts a serve and shutdown function pair to the Lifecycle interface.
+type server struct {
+	serve    func() error
+	shutdown func(ctx context.Context) error
+	stopping int32
+}
+
+// Init does nothing; the server is set up by the caller.
+func (s *server) Init() error {
+	atomic.StoreInt32(&s.stopping, 0)
+	return nil
+}
+
+// Start serves until the server is shut down.
+func (s *server) Start() error {
+	err := s.serve()
+	if atomic.LoadInt32(&s.stopping) == 1 {
+		return nil
+	}
+	return err
+}
+
+// Stop shuts the server down, waiting up to DefaultShutdownTimeout.
+func (s *server) Stop() error {
+	return s.StopContext(context.Background())
+}
+
+// StopContext stops the server as Stop does, but by the time ctx expires at
+// the latest.
+func (s *server) StopContext(ctx context.Context) error {
+	atomic.StoreInt32(&s.stopping, 1)
+	ctx, cancel := withTimeout(ctx, systemClock{}, DefaultShutdownTimeout)
+	defer cancel()
+	return s.shutdown(ctx)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+
+	log "github.com/uber-go/zap"
+)
+
+// shardSet is a family of components added with AddSharded, one per tenant.
+type shardSet struct {
+	name    string
+	tenants func(cfg interface{}) []string
+	create  func(tenant string) (Lifecycle, error)
+	opts    []ComponentOption
+}
+
+// AddSharded adds a component per tenant, such as a customer or shard,
+// created by calling create with the tenant's name. The tenants are listed by
+// tenants from the Group's configuration during Init and on every Reload:
+// components are created for tenants that have been added, and stopped and
+// removed for tenants that have been removed, while the remaining components
+// are reloaded as usual. Each component is named name/tenant and configured
+// by opts. AddSharded must be called before the Group is initialized.
+func (g *Group) AddSharded(name string, tenants func(cfg interface{}) []string,
+	create func(tenant string) (Lifecycle, error), opts ...ComponentOption) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.shards = append(g.shards, &shardSet{name: name, tenants: tenants, create: create, opts: opts})
+}
+
+// reshard brings the sharded components in line with the tenants listed by
+// cfg, stopping and removing components of removed tenants and adding, but
+// not initializing, components for added tenants. The caller must hold g.mu.
+func (g *Group) reshard(cfg interface{}) error {
+	for _, s := range g.shards {
+		tenants := s.tenants(cfg)
+		want := make(map[string]bool, len(tenants))
+		for _, tenant := range tenants {
+			want[s.name+"/"+tenant] = true
+		}
+
+		kept := g.components[:0]
+		var first error
+		for _, c := range g.components {
+			if c.shard != s || want[c.name] {
+				kept = append(kept, c)
+				continue
+			}
+			msgTenantRemoved.emit(
+				log.String("component", c.name))
+			if c.initialized {
+				if err := g.stop(c); err != nil && first == nil {
+					first = err
+				}
+			}
+		}
+		for i := len(kept); i < len(g.components); i++ {
+			g.components[i] = nil
+		}
+		g.components = kept
+		if first != nil {
+			return first
+		}
+
+		for _, tenant := range tenants {
+			name := s.name + "/" + tenant
+			if g.lookup(name) >= 0 {
+				continue
+			}
+			lc, err := s.create(tenant)
+			if err != nil {
+				return fmt.Errorf("component %s: create: %w", name, err)
+			}
+			c := &component{name: name, lc: lc, shard: s}
+			for _, opt := range s.opts {
+				opt(c)
+			}
+			g.components = append(g.components, c)
+			msgTenantAdded.emit(
+				log.String("component", name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SignalStat counts the deliveries of a signal.
+type SignalStat struct {
+	Signal string `json:"signal"`
+	// Received is the number of times the signal was received.
+	Received uint64 `json:"received"`
+	// Ignored is the number of times the signal was received but had no
+	// effect, such as SIGHUP for a lifecycle not implementing Reloader.
+	Ignored uint64 `json:"ignored"`
+	// Last is when the signal was last received.
+	Last time.Time `json:"last"`
+}
+
+// SignalStats returns the statistics of every signal received, ordered by
+// signal name.
+func (d *Dissembler) SignalStats() []SignalStat {
+	d.sigMu.Lock()
+	defer d.sigMu.Unlock()
+
+	stats := make([]SignalStat, 0, len(d.sigStats))
+	for _, s := range d.sigStats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Signal < stats[j].Signal
+	})
+	return stats
+}
+
+// recordSignal counts a delivery of sig, which was ignored if ignored is set.
+func (d *Dissembler) recordSignal(sig os.Signal, ignored bool) {
+	d.sigMu.Lock()
+	defer d.sigMu.Unlock()
+
+	name := sig.String()
+	s, ok := d.sigStats[name]
+	if !ok {
+		if d.sigStats == nil {
+			d.sigStats = make(map[string]*SignalStat)
+		}
+		s = &SignalStat{Signal: name}
+		d.sigStats[name] = s
+	}
+	s.Received++
+	if ignored {
+		s.Ignored++
+	}
+	s.Last = clockOr(d.clock).Now()
+}
+
+// signalTable formats the signal statistics as a table.
+func (d *Dissembler) signalTable() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SIGNAL\tRECEIVED\tIGNORED\tLAST")
+	for _, s := range d.SignalStats() {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n",
+			s.Signal, s.Received, s.Ignored, s.Last.Format(time.RFC3339))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// exportSignals exports the signal statistics in Metrics.
+func (d *Dissembler) exportSignals() {
+	Metrics.Set(metricSignals, expvar.Func(func() interface{} {
+		return d.SignalStats()
+	}))
+}
@@ -0,0 +1,108 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// EventSink streams lifecycle events to an external pipeline, such as a
+// message broker, for fleet-wide auditing and capacity planning. The natssink
+// and kafkasink packages provide NATS and Kafka implementations.
+type EventSink interface {
+	// Send delivers a batch of events. It is called from a single goroutine,
+	// and may block without stalling the lifecycle; events arriving
+	// meanwhile are queued and, once the queue is full, dropped.
+	Send(events []Event) error
+}
+
+// WithEventSink streams events to sink for the life of Serve. The sink's
+// subscription is configured by opts. Failures to send are logged and the
+// batch is discarded. When Serve returns, it waits up to SinkFlushTimeout for
+// the final events, such as the lifecycle stopping, to be sent.
+func WithEventSink(sink EventSink, opts ...SubscribeOption) Option {
+	return func(d *Dissembler) {
+		d.sinks = append(d.sinks, eventSink{sink: sink, opts: opts})
+	}
+}
+
+// SinkFlushTimeout bounds how long Serve waits for event sinks to send the
+// events queued when it returns.
+const SinkFlushTimeout = 5 * time.Second
+
+// eventSink is a sink added with WithEventSink.
+type eventSink struct {
+	sink EventSink
+	opts []SubscribeOption
+}
+
+// subscribe subscribes the sink to events.
+func (s eventSink) subscribe() *Subscription {
+	return Subscribe(func(events []Event) {
+		if err := s.sink.Send(events); err != nil {
+			msgUnableToSendEvents.emit(
+				log.Int("events", len(events)),
+				log.String("error", err.Error()),
+			)
+		}
+	}, s.opts...)
+}
+
+// hostname identifies the host in encoded events.
+var hostname, _ = os.Hostname()
+
+// MarshalJSON encodes the event with the host, process and, if detected,
+// cloud instance it occurred in, for sinks aggregating events from many
+// processes.
+func (e Event) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Type           string    `json:"type"`
+		Time           time.Time `json:"time"`
+		Host           string    `json:"host,omitempty"`
+		PID            int       `json:"pid"`
+		RunID          string    `json:"run_id,omitempty"`
+		State          string    `json:"state,omitempty"`
+		Component      string    `json:"component,omitempty"`
+		ComponentState string    `json:"component_state,omitempty"`
+		Signal         string    `json:"signal,omitempty"`
+		Path           string    `json:"path,omitempty"`
+		DiskLevel      string    `json:"disk_level,omitempty"`
+		DiskFree       int64     `json:"disk_free,omitempty"`
+		Error          string    `json:"error,omitempty"`
+		Instance       *Instance `json:"instance,omitempty"`
+	}{
+		Type:  e.Type.String(),
+		Time:  e.Time,
+		Host:  hostname,
+		PID:   os.Getpid(),
+		RunID: e.RunID,
+	}
+
+	switch e.Type {
+	case EventState:
+		v.State = e.State.String()
+	case EventComponent:
+		v.Component = e.Component
+		v.ComponentState = e.ComponentState.String()
+	case EventSignal:
+		if e.Signal != nil {
+			v.Signal = e.Signal.String()
+		}
+	case EventDisk:
+		v.Path = e.Path
+		v.DiskLevel = e.DiskLevel.String()
+		v.DiskFree = e.DiskFree
+	}
+	if e.Err != nil {
+		v.Error = e.Err.Error()
+	}
+	if i, ok := CloudInstance(); ok {
+		v.Instance = &i
+	}
+	return json.Marshal(v)
+}
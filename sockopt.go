@@ -0,0 +1,118 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"crypto/tls"
+	"net"
+	"syscall"
+	"time"
+)
+
+// ListenOption tunes a socket opened through Listen, ListenPacket or
+// ListenAll. Options set on the socket itself, such as ReusePort, take effect
+// when the socket is created; a socket handed down by an upgrade keeps the
+// options its parent set. Socket options other than KeepAlive are only
+// supported on Linux; elsewhere, Listen fails if they are given.
+type ListenOption func(*listenConfig)
+
+// listenConfig is the listener configuration requested by ListenOptions.
+type listenConfig struct {
+	name        string
+	dualStack   *bool
+	reusePort   bool
+	keepAlive   time.Duration
+	deferAccept time.Duration
+
+	proxyTimeout time.Duration
+	tls          *tls.Config
+}
+
+// DualStack controls whether an IPv6 socket also accepts IPv4 traffic. Go
+// listens on both for the unspecified address of the "tcp" and "udp" networks
+// and on IPv6 only for "tcp6" and "udp6"; DualStack(false) binds "[::]" to IPv6
+// only, so a separate IPv4 socket can bind the same port.
+func DualStack(on bool) ListenOption {
+	return func(c *listenConfig) {
+		c.dualStack = &on
+	}
+}
+
+// ReusePort sets SO_REUSEPORT, so several sockets, in this process or
+// others, may bind the same address and the kernel balances connections or
+// packets between them.
+func ReusePort() ListenOption {
+	return func(c *listenConfig) {
+		c.reusePort = true
+	}
+}
+
+// KeepAlive sets the TCP keep-alive period of accepted connections. A
+// negative period disables keep-alives; zero leaves Go's default.
+func KeepAlive(period time.Duration) ListenOption {
+	return func(c *listenConfig) {
+		c.keepAlive = period
+	}
+}
+
+// DeferAccept sets TCP_DEFER_ACCEPT, so connections are only accepted once
+// the client has sent data, or dropped if it has sent none within timeout,
+// sparing the server idle connections.
+func DeferAccept(timeout time.Duration) ListenOption {
+	return func(c *listenConfig) {
+		c.deferAccept = timeout
+	}
+}
+
+// newListenConfig returns the configuration requested by opts.
+func newListenConfig(opts []ListenOption) *listenConfig {
+	c := &listenConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// sockopts reports whether any option must be set on the socket itself.
+func (c *listenConfig) sockopts() bool {
+	return c.dualStack != nil || c.reusePort || c.deferAccept > 0
+}
+
+// netConfig returns the net.ListenConfig creating sockets tuned by c.
+func (c *listenConfig) netConfig() *net.ListenConfig {
+	lc := &net.ListenConfig{}
+	if c.sockopts() {
+		lc.Control = c.control
+	}
+	return lc
+}
+
+// control sets the socket options on a socket before it is bound.
+func (c *listenConfig) control(network, address string, rc syscall.RawConn) error {
+	var err error
+	if cerr := rc.Control(func(fd uintptr) {
+		err = c.setSockopts(network, fd)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// tuneConn applies the keep-alive period to an accepted connection and
+// wraps it to read its PROXY header if ProxyProtocol is set and to terminate
+// TLS if TLS is set.
+func (c *listenConfig) tuneConn(conn net.Conn) net.Conn {
+	if tc, ok := conn.(*net.TCPConn); ok && c.keepAlive != 0 {
+		if c.keepAlive < 0 {
+			tc.SetKeepAlive(false)
+		} else {
+			tc.SetKeepAlive(true)
+			tc.SetKeepAlivePeriod(c.keepAlive)
+		}
+	}
+	if c.proxyTimeout > 0 {
+		conn = newProxyConn(conn, c.proxyTimeout)
+	}
+	return c.serveTLS(conn)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// soReusePort is SO_REUSEPORT, missing from package syscall on Linux.
+const soReusePort = 0xf
+
+// setSockopts sets the socket options requested by c on fd.
+func (c *listenConfig) setSockopts(network string, fd uintptr) error {
+	s := int(fd)
+	if c.reusePort {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, soReusePort, 1); err != nil {
+			return os.NewSyscallError("setsockopt SO_REUSEPORT", err)
+		}
+	}
+	if c.dualStack != nil {
+		sa, err := syscall.Getsockname(s)
+		if err != nil {
+			return os.NewSyscallError("getsockname", err)
+		}
+		if _, ok := sa.(*syscall.SockaddrInet6); ok {
+			v6Only := 1
+			if *c.dualStack {
+				v6Only = 0
+			}
+			if err := syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, v6Only); err != nil {
+				return os.NewSyscallError("setsockopt IPV6_V6ONLY", err)
+			}
+		}
+	}
+	if c.deferAccept > 0 && strings.HasPrefix(network, "tcp") {
+		secs := int((c.deferAccept + time.Second - 1) / time.Second)
+		if err := syscall.SetsockoptInt(s, syscall.IPPROTO_TCP, syscall.TCP_DEFER_ACCEPT, secs); err != nil {
+			return os.NewSyscallError("setsockopt TCP_DEFER_ACCEPT", err)
+		}
+	}
+	return nil
+}
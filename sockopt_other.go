@@ -0,0 +1,17 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package dissembler
+
+import (
+	"errors"
+	"runtime"
+)
+
+// setSockopts is not supported outside Linux.
+func (c *listenConfig) setSockopts(network string, fd uintptr) error {
+	return errors.New("socket options are not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// standbyPoll is how often ActivateOnFile checks for its file.
+const standbyPoll = time.Second
+
+// Activator waits for the lifecycle of a standby to be activated, such as by
+// winning a leader election, returning nil once it should be. It should
+// return once ctx is cancelled, as it is when the lifecycle is activated by
+// other means or stops. An error is logged and the activator given up.
+type Activator func(ctx context.Context) error
+
+// WithStandby starts the lifecycle in warm standby, for active/passive pairs:
+// it is initialized as usual, but started only once activated, by the first
+// of activators to return nil, by Activate, or by the control command
+// "activate". Until then the Dissembler handles signals, so a standby may be
+// reloaded or stopped, and is not ready. Whether it is standby or active is
+// shown in the status and exported as the "role" metric. WithFence guards
+// against both of a pair being active at once.
+func WithStandby(activators ...Activator) Option {
+	return func(d *Dissembler) {
+		d.standby = true
+		d.activators = append(d.activators, activators...)
+	}
+}
+
+// ActivateOnFile returns an Activator activating the lifecycle once a file
+// exists at path, as created by a failover script.
+func ActivateOnFile(path string) Activator {
+	return func(ctx context.Context) error {
+		t := systemClock{}.NewTicker(standbyPoll)
+		defer t.Stop()
+		for {
+			if _, err := os.Stat(path); err == nil {
+				return nil
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+			select {
+			case <-t.C():
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Activate starts the lifecycle of a standby set up by WithStandby. It does
+// nothing if the lifecycle is already active or not in standby.
+func (d *Dissembler) Activate() {
+	d.activateOnce.Do(func() {
+		close(d.activated)
+	})
+}
+
+// Active reports whether the lifecycle has been started, which for a standby
+// means activated.
+func (d *Dissembler) Active() bool {
+	return atomic.LoadInt32(&d.active) == 1
+}
+
+// role returns "standby" or "active" for a standby, or "" otherwise.
+func (d *Dissembler) role() string {
+	switch {
+	case !d.standby:
+		return ""
+	case atomic.LoadInt32(&d.fenced) == 1:
+		return "fenced"
+	case d.Active():
+		return "active"
+	}
+	return "standby"
+}
+
+// standBy starts the lifecycle once it is activated and, with WithFence, its
+// lease is acquired.
+func (d *Dissembler) standBy(ctx context.Context) error {
+	if !d.awaitActivation(ctx) {
+		return nil
+	}
+	if d.fence != nil {
+		lost, err := d.fence(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("acquiring lease: %w", err)
+		}
+		msgLeaseAcquired.emit()
+		d.spawn("lease", func(ctx context.Context) error {
+			return d.watchLease(ctx, lost)
+		})
+	}
+	d.start()
+	return nil
+}
+
+// awaitActivation waits in standby until the lifecycle is activated,
+// returning false if ctx is cancelled first.
+func (d *Dissembler) awaitActivation(ctx context.Context) bool {
+	Metrics.Set(metricRole, expvar.Func(func() interface{} {
+		return d.role()
+	}))
+	msgStandby.emit()
+
+	actx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, a := range d.activators {
+		go func(a Activator) {
+			if err := a(actx); err == nil {
+				d.Activate()
+			} else if actx.Err() == nil {
+				msgActivatorFailed.emit(
+					log.String("error", err.Error()),
+				)
+			}
+		}(a)
+	}
+
+	select {
+	case <-d.activated:
+		msgActivated.emit()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+)
+
+// State is the state of a Dissembler.
+type State int32
+
+const (
+	// StateIdle is a Dissembler that has not begun serving.
+	StateIdle State = iota
+	// StateStarting is a Dissembler whose lifecycle is initializing or
+	// starting and is not yet ready.
+	StateStarting
+	// StateRunning is a Dissembler whose lifecycle is ready.
+	StateRunning
+	// StateReloading is a Dissembler reloading its lifecycle.
+	StateReloading
+	// StateUpgrading is a Dissembler handing over to an upgraded process.
+	StateUpgrading
+	// StateStopping is a Dissembler stopping its lifecycle.
+	StateStopping
+	// StateStopped is a Dissembler that has finished serving.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateReloading:
+		return "reloading"
+	case StateUpgrading:
+		return "upgrading"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// State returns the current state. It does not lock, so health and metrics
+// endpoints may poll it at any rate.
+func (d *Dissembler) State() State {
+	return State(atomic.LoadInt32(&d.state))
+}
+
+// setState records a new state. Transitions are serialized by stateMu, while
+// State reads the value atomically.
+func (d *Dissembler) setState(s State) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	d.storeState(s)
+}
+
+// storeState records a new state, publishing an EventState if it changed.
+// stateMu must be held.
+func (d *Dissembler) storeState(s State) {
+	if from := State(atomic.SwapInt32(&d.state, int32(s))); from != s {
+		if !LegalTransition(from, s) {
+			violate("state moved from %s to %s", from, s)
+		}
+		publish(Event{Type: EventState, Time: clockOr(d.clock).Now(), State: s})
+		if d.stateChanged != nil {
+			close(d.stateChanged)
+			d.stateChanged = nil
+		}
+	}
+}
+
+// StateIs reports whether the current state is one of states.
+func (d *Dissembler) StateIs(states ...State) bool {
+	current := d.State()
+	for _, s := range states {
+		if s == current {
+			return true
+		}
+	}
+	return false
+}
+
+// AwaitState waits until the current state is one of states, so orchestration
+// code, tests and sidecars can synchronize on the progress of the lifecycle,
+// returning ctx's error if it is cancelled first. Transient states, such as
+// StateReloading, may be missed if they end before the waiter observes them.
+// To not wait forever on a lifecycle that fails to start, await StateStopped
+// too:
+//
+//	err := d.AwaitState(ctx, dissembler.StateRunning, dissembler.StateStopped)
+func (d *Dissembler) AwaitState(ctx context.Context, states ...State) error {
+	for {
+		d.stateMu.Lock()
+		ok := d.StateIs(states...)
+		if d.stateChanged == nil {
+			d.stateChanged = make(chan struct{})
+		}
+		changed := d.stateChanged
+		d.stateMu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// markReady records that the lifecycle became ready, entering StateRunning
+// unless a reload or upgrade is in progress, in which case the state is
+// settled once it ends.
+func (d *Dissembler) markReady() {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	d.booted = true
+	if d.State() == StateStarting {
+		d.storeState(StateRunning)
+	}
+}
+
+// transition enters the transient state s, such as StateReloading, returning
+// the function that settles the state once the transition ends.
+func (d *Dissembler) transition(s State) (end func()) {
+	d.setState(s)
+	return func() {
+		d.stateMu.Lock()
+		defer d.stateMu.Unlock()
+		if d.State() != s {
+			return
+		}
+		if d.booted {
+			d.storeState(StateRunning)
+		} else {
+			d.storeState(StateStarting)
+		}
+	}
+}
+
+// exportState exports the state and configuration generation in Metrics.
+func (d *Dissembler) exportState() {
+	Metrics.Set(metricState, expvar.Func(func() interface{} {
+		return d.State().String()
+	}))
+	Metrics.Set(metricGeneration, expvar.Func(func() interface{} {
+		return d.Generation()
+	}))
+}
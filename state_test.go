@@ -0,0 +1,90 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// running returns a Dissembler in StateRunning, without serving.
+func running() *Dissembler {
+	d := New(&stopTimer{stopped: make(chan time.Time, 1)})
+	d.setState(StateStarting)
+	d.markReady()
+	return d
+}
+
+// BenchmarkState measures State polled concurrently, as by health and metrics
+// endpoints.
+func BenchmarkState(b *testing.B) {
+	d := running()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if d.State() != StateRunning {
+				b.Fatal("not running")
+			}
+		}
+	})
+}
+
+// BenchmarkStateDuringReloads measures State polled concurrently while the
+// Dissembler transitions in and out of StateReloading.
+func BenchmarkStateDuringReloads(b *testing.B) {
+	discardLogs(b)
+	d := running()
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			end := d.transition(StateReloading)
+			end()
+		}
+	}()
+	defer func() {
+		close(quit)
+		<-done
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if !d.StateIs(StateRunning, StateReloading) {
+				b.Fatal("not running")
+			}
+		}
+	})
+}
+
+// BenchmarkGroupReady measures the readiness of a Group polled concurrently,
+// which reads the status of every component.
+func BenchmarkGroupReady(b *testing.B) {
+	g := &Group{}
+	for i := 0; i < 8; i++ {
+		g.Add(fmt.Sprintf("component-%d", i), &stopTimer{})
+	}
+	now := time.Now()
+	for _, c := range g.components {
+		c.initialized = true
+		c.setState(ComponentRunning, nil, now)
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := g.Ready(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
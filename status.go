@@ -0,0 +1,242 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recentEvents is the number of events shown on the admin status page.
+const recentEvents = 50
+
+// StatusReporter is an optional interface that may be implemented by a
+// Lifecycle composed of components, such as Group, to report their status on
+// the admin status page.
+type StatusReporter interface {
+	Status() []ComponentStatus
+}
+
+// Status is the status of a Dissembler, as served by the admin API.
+type Status struct {
+	Version     string            `json:"version"`
+	GitCommit   string            `json:"git_commit,omitempty"`
+	GoVersion   string            `json:"go_version"`
+	PID         int               `json:"pid"`
+	RunID       string            `json:"run_id"`
+	Incarnation uint64            `json:"incarnation,omitempty"`
+	Restarts    uint64            `json:"restarts,omitempty"`
+	StopAt      *time.Time        `json:"stop_at,omitempty"`
+	State       string            `json:"state"`
+	Generation  uint64            `json:"generation"`
+	Quiesced    bool              `json:"quiesced,omitempty"`
+	Role        string            `json:"role,omitempty"`
+	Started     time.Time         `json:"started"`
+	Instance    *Instance         `json:"instance,omitempty"`
+	TLS         *TLSPolicy        `json:"tls,omitempty"`
+	Components  []componentReport `json:"components,omitempty"`
+	Events      []eventReport     `json:"events,omitempty"`
+}
+
+// componentReport is a component's row of the status page.
+type componentReport struct {
+	Name     string        `json:"name"`
+	State    string        `json:"state"`
+	Critical bool          `json:"critical"`
+	Since    time.Time     `json:"since"`
+	Uptime   time.Duration `json:"uptime_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// eventReport is an event's row of the status page.
+type eventReport struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Detail string    `json:"detail"`
+}
+
+// eventLog keeps the most recent events for the status page.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+}
+
+// record appends events, overwriting the oldest once recentEvents are kept.
+func (l *eventLog) record(events []Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range events {
+		if len(l.events) < recentEvents {
+			l.events = append(l.events, e)
+			continue
+		}
+		l.events[l.next] = e
+		l.next = (l.next + 1) % recentEvents
+	}
+}
+
+// recent returns the kept events, newest first.
+func (l *eventLog) recent() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := make([]Event, 0, len(l.events))
+	for i := len(l.events) - 1; i >= 0; i-- {
+		events = append(events, l.events[(l.next+i)%len(l.events)])
+	}
+	return events
+}
+
+// status returns the current status.
+func (d *Dissembler) status() Status {
+	s := Status{
+		Version:     fullVersion(),
+		GitCommit:   GitCommit,
+		GoVersion:   runtime.Version(),
+		PID:         os.Getpid(),
+		RunID:       RunID(),
+		Incarnation: d.incarnation,
+		Restarts:    d.Restarts(),
+		State:       d.State().String(),
+		Generation:  d.Generation(),
+		Quiesced:    atomic.LoadInt32(&d.quiesced) == 1,
+		Role:        d.role(),
+		Started:     d.started,
+		TLS:         d.adminPolicy,
+	}
+
+	if at := d.ScheduledStop(); !at.IsZero() {
+		s.StopAt = &at
+	}
+	if i, ok := CloudInstance(); ok {
+		s.Instance = &i
+	}
+
+	now := clockOr(d.clock).Now()
+	if r, ok := d.lifecycle.(StatusReporter); ok {
+		for _, c := range r.Status() {
+			cr := componentReport{
+				Name:     c.Name,
+				State:    c.State.String(),
+				Critical: c.Critical,
+				Since:    c.Since,
+			}
+			if c.State == ComponentRunning {
+				cr.Uptime = now.Sub(c.Since)
+			}
+			if c.Err != nil {
+				cr.Error = c.Err.Error()
+			}
+			s.Components = append(s.Components, cr)
+		}
+	}
+
+	for _, e := range d.events.recent() {
+		s.Events = append(s.Events, eventReport{
+			Time:   e.Time,
+			Type:   e.Type.String(),
+			Detail: eventDetail(e),
+		})
+	}
+	return s
+}
+
+// eventDetail describes an event in a few words.
+func eventDetail(e Event) string {
+	switch e.Type {
+	case EventState:
+		return e.State.String()
+	case EventComponent:
+		detail := e.Component + " " + e.ComponentState.String()
+		if e.Err != nil {
+			detail += ": " + e.Err.Error()
+		}
+		return detail
+	case EventSignal:
+		return e.Signal.String()
+	case EventDisk:
+		return e.Path + " " + e.DiskLevel.String() + ", " + formatBytes(e.DiskFree) + " free"
+	}
+	return ""
+}
+
+// adminStatus serves the status as JSON, or as an HTML page to browsers.
+func (d *Dissembler) adminStatus(w http.ResponseWriter, r *http.Request) {
+	s := d.status()
+	if r.URL.Query().Get("format") == "html" ||
+		(r.URL.Query().Get("format") == "" && strings.Contains(r.Header.Get("Accept"), "text/html")) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		statusPage.Execute(w, s)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// statusPage renders a Status as HTML.
+var statusPage = template.Must(template.New("status").Funcs(template.FuncMap{
+	"uptime": func(d time.Duration) string {
+		if d == 0 {
+			return "-"
+		}
+		return d.Truncate(time.Second).String()
+	},
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dissembler status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.degraded { color: #b00; }
+</style>
+</head>
+<body>
+<h1>dissembler {{.Version}}</h1>
+<table>
+<tr><th>State</th><td>{{.State}}</td></tr>
+<tr><th>Configuration generation</th><td>{{.Generation}}</td></tr>
+{{if .Quiesced}}<tr><th>Quiesced</th><td>yes</td></tr>{{end}}
+{{if .Role}}<tr><th>Role</th><td>{{.Role}}</td></tr>{{end}}
+<tr><th>Started</th><td>{{time .Started}}</td></tr>
+<tr><th>PID</th><td>{{.PID}}</td></tr>
+<tr><th>Run</th><td>{{.RunID}}</td></tr>
+{{if .Incarnation}}<tr><th>Incarnation</th><td>{{.Incarnation}}</td></tr>{{end}}
+{{if .Restarts}}<tr><th>Restarts</th><td>{{.Restarts}}</td></tr>{{end}}
+{{with .StopAt}}<tr><th>Scheduled stop</th><td>{{time .}}</td></tr>{{end}}
+<tr><th>Go</th><td>{{.GoVersion}}</td></tr>
+{{if .GitCommit}}<tr><th>Commit</th><td>{{.GitCommit}}</td></tr>{{end}}
+{{with .TLS}}<tr><th>Admin TLS</th><td>{{.MinVersion}} to {{.MaxVersion}}{{if .Approved}}, approved cryptography{{end}}{{if .FIPS140}}, FIPS 140-3 mode{{end}}</td></tr>{{end}}
+{{with .Instance}}<tr><th>Instance</th><td>{{.Provider}} {{.ID}} ({{.Region}}{{if .Zone}}, {{.Zone}}{{end}})</td></tr>{{end}}
+</table>
+{{if .Components}}
+<h2>Components</h2>
+<table>
+<tr><th>Name</th><th>State</th><th>Critical</th><th>Uptime</th><th>Last error</th></tr>
+{{range .Components}}<tr class="{{.State}}"><td>{{.Name}}</td><td>{{.State}}</td><td>{{.Critical}}</td><td>{{uptime .Uptime}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Recent events</h2>
+<table>
+<tr><th>Time</th><th>Type</th><th>Detail</th></tr>
+{{range .Events}}<tr><td>{{time .Time}}</td><td>{{.Type}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
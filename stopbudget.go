@@ -0,0 +1,84 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrStopBudget is recorded as the stop error when the lifecycle does not stop
+// within the budget set by WithStopBudget for the terminating signal.
+var ErrStopBudget = errors.New("lifecycle did not stop within its budget")
+
+// stopSignalKey is the context key of the terminating signal.
+type stopSignalKey struct{}
+
+// WithStopBudget bounds how long stopping the lifecycle may take when it is
+// stopped by sig, one of SIGINT, SIGQUIT and SIGTERM, so each may stop
+// differently. For instance, SIGINT from an operator at a terminal can stop
+// quickly while SIGTERM from an orchestrator drains fully:
+//
+//	dissembler.WithStopBudget(syscall.SIGINT, 5*time.Second)
+//	dissembler.WithStopBudget(syscall.SIGTERM, 30*time.Second)
+//
+// The context given to StopContext expires once the budget is spent; Group
+// passes it on to its components and HTTPServer shuts down by then at the
+// latest. If the lifecycle has not stopped by then, stopping carries on without
+// it, recording ErrStopBudget. Stops requested through the control socket count
+// as SIGTERM. Signals without a budget stop without a deadline.
+func WithStopBudget(sig os.Signal, budget time.Duration) Option {
+	return func(d *Dissembler) {
+		if d.stopBudgets == nil {
+			d.stopBudgets = make(map[os.Signal]time.Duration)
+		}
+		d.stopBudgets[sig] = budget
+	}
+}
+
+// StopSignal returns the terminating signal carried by the context given to
+// StopContext, so a lifecycle can choose how to stop, such as skipping a
+// lengthy drain on SIGINT.
+func StopSignal(ctx context.Context) (os.Signal, bool) {
+	sig, ok := ctx.Value(stopSignalKey{}).(os.Signal)
+	return sig, ok
+}
+
+// stopContext returns ctx carrying the terminating signal sig and expiring
+// once its budget, if any, is spent.
+func (d *Dissembler) stopContext(ctx context.Context, sig os.Signal) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, stopSignalKey{}, sig)
+	if budget, ok := d.stopBudgets[sig]; ok {
+		return withTimeout(ctx, clockOr(d.clock), budget)
+	}
+	return context.WithCancel(ctx)
+}
+
+// stopLifecycle stops the lifecycle, giving up with ErrStopBudget if it was
+// stopped by a signal with a budget and ctx expires first.
+func (d *Dissembler) stopLifecycle(ctx context.Context) error {
+	stop := func() error {
+		if s, ok := d.lifecycle.(ContextStopper); ok {
+			return s.StopContext(ctx)
+		}
+		return d.lifecycle.Stop()
+	}
+	sig, _ := StopSignal(ctx)
+	if _, ok := d.stopBudgets[sig]; !ok {
+		return stop()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- stop()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ErrStopBudget
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	log "github.com/uber-go/zap"
+)
+
+// ErrStrict is wrapped by the errors WithStrict makes of contract violations.
+var ErrStrict = errors.New("strict mode violation")
+
+// WithStrict turns the soft warnings Dissembler logs about sloppy lifecycles
+// into hard errors, so CI and staging catch them before production:
+//
+//   - options the lifecycle cannot honor, such as WithMaxWork for a lifecycle
+//     not implementing WorkCounter, WithReloadInterval for one not
+//     implementing Reloader, or WithStopBudget for one not implementing
+//     ContextStopper, fail Serve before Init, with ExitSetupFailed;
+//   - signals the lifecycle does not handle, such as SIGUSR1, or SIGHUP for a
+//     lifecycle not implementing Reloader, stop it with ExitFailed;
+//   - a stop after which goroutines started with Go are still running, such
+//     as a Start that Stop does not end, OnStop hooks exceeding their
+//     budgets, and any invariant violation recorded, as CheckInvariants
+//     reports, fail the stop, with ExitStopFailed.
+func WithStrict() Option {
+	return func(d *Dissembler) {
+		d.strict = true
+	}
+}
+
+// checkStrict returns an error naming every option the lifecycle cannot
+// honor, in strict mode.
+func (d *Dissembler) checkStrict() error {
+	if !d.strict {
+		return nil
+	}
+	var problems []string
+	if _, ok := d.lifecycle.(WorkCounter); d.maxWork > 0 && !ok {
+		problems = append(problems, "WithMaxWork: lifecycle does not implement WorkCounter")
+	}
+	if d.reloadInterval > 0 && !canReload(d.lifecycle) {
+		problems = append(problems, "WithReloadInterval: lifecycle does not implement Reloader")
+	}
+	if _, ok := d.lifecycle.(Rollbacker); d.bake > 0 && !ok {
+		problems = append(problems, "WithReloadBake: lifecycle does not implement Rollbacker")
+	}
+	if _, ok := d.lifecycle.(ContextStopper); len(d.stopBudgets) > 0 && !ok {
+		problems = append(problems, "WithStopBudget: lifecycle does not implement ContextStopper")
+	}
+	if d.fence != nil && !d.standby {
+		problems = append(problems, "WithFence: lifecycle not in standby")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return d.strictError(strings.Join(problems, "; "))
+}
+
+// failStrict stops the lifecycle, in strict mode, once it was sent a signal
+// it does not handle, as problem describes.
+func (d *Dissembler) failStrict(ctx context.Context, problem string) (syscall.Signal, error) {
+	err := d.strictError(problem)
+	d.exitReason = ExitFailed
+	d.stop(ctx)
+	return 0, err
+}
+
+// checkStrictStop returns an error describing what went wrong stopping the
+// lifecycle, as reported by r, in strict mode.
+func (d *Dissembler) checkStrictStop(r ShutdownReport) error {
+	if !d.strict {
+		return nil
+	}
+	var problems []string
+	if len(r.Goroutines) > 0 {
+		problems = append(problems, "goroutines still running after stop: "+strings.Join(r.Goroutines, ", "))
+	}
+	for _, h := range r.Hooks {
+		if errors.Is(h.Err, ErrHookBudget) {
+			problems = append(problems, fmt.Sprintf("hook %s exceeded its budget", h.Name))
+		}
+	}
+	problems = append(problems, recordedViolations()...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return d.strictError(strings.Join(problems, "; "))
+}
+
+// strictError logs and returns the strict mode violation problem.
+func (d *Dissembler) strictError(problem string) error {
+	msgStrictViolation.emit(
+		log.String("error", problem),
+	)
+	return fmt.Errorf("%w: %s", ErrStrict, problem)
+}
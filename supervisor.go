@@ -0,0 +1,379 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+const (
+	// DefaultMaxRestarts is the number of restarts a Supervisor allows within
+	// its Period when MaxRestarts is not set.
+	DefaultMaxRestarts = 3
+	// DefaultRestartPeriod is the window in which a Supervisor counts restarts
+	// when Period is not set.
+	DefaultRestartPeriod = 5 * time.Second
+)
+
+// Strategy determines which children a Supervisor restarts when one of them
+// fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that failed.
+	OneForOne Strategy = iota
+	// OneForAll stops every other child and restarts all of them when one
+	// fails.
+	OneForAll
+	// RestForOne stops the children added after the failed child and restarts
+	// the failed child together with them.
+	RestForOne
+)
+
+// String returns the Erlang name of the strategy.
+func (s Strategy) String() string {
+	switch s {
+	case OneForOne:
+		return "one_for_one"
+	case OneForAll:
+		return "one_for_all"
+	case RestForOne:
+		return "rest_for_one"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// Supervisor is a Lifecycle that restarts its children, in the manner of an
+// Erlang supervisor, when their Start returns an error. A child whose Start
+// returns nil has finished and is not restarted. As in Erlang, a restart
+// starts a child afresh: the failed child is stopped, and each child being
+// restarted is initialized again before its Start is called. A child whose
+// Init fails on restart fails the Supervisor.
+//
+// A Supervisor may be added to a Group or to another Supervisor. When its
+// children fail more than MaxRestarts times within Period, the Supervisor
+// stops every child and its own Start returns the failure, escalating it to
+// the parent.
+type Supervisor struct {
+	// Strategy selects which children are restarted when one fails.
+	Strategy Strategy
+	// MaxRestarts is the number of restarts allowed within Period. If zero,
+	// DefaultMaxRestarts is used.
+	MaxRestarts int
+	// Period is the window in which restarts are counted. If zero,
+	// DefaultRestartPeriod is used.
+	Period time.Duration
+	// Clock measures the restart intensity. If nil, the system clock is used.
+	Clock Clock
+
+	mu       sync.Mutex
+	children []*child
+	restarts []time.Time
+	exits    chan exit
+	quit     chan struct{}
+	stopping bool
+}
+
+// child is a named Lifecycle managed by a Supervisor.
+type child struct {
+	name    string
+	lc      Lifecycle
+	running bool
+	gen     int
+}
+
+// exit reports the return of a child's Start, after running for elapsed. The
+// generation distinguishes exits of earlier runs of a restarted child.
+type exit struct {
+	child   *child
+	gen     int
+	err     error
+	elapsed time.Duration
+}
+
+// Add appends a named child to the Supervisor. Children must be added before
+// the Supervisor is initialized.
+func (s *Supervisor) Add(name string, lc Lifecycle) {
+	s.mu.Lock()
+	s.children = append(s.children, &child{name: name, lc: lc})
+	s.mu.Unlock()
+}
+
+// Init initializes every child in order.
+func (s *Supervisor) Init() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exits = make(chan exit)
+	s.quit = make(chan struct{})
+	s.stopping = false
+	clock := clockOr(s.Clock)
+	for _, c := range s.children {
+		begin := clock.Now()
+		if err := c.lc.Init(); err != nil {
+			return phaseError(c.name, PhaseInit, 1, clock.Now().Sub(begin), err)
+		}
+	}
+	return nil
+}
+
+// Start starts every child and supervises them until the Supervisor is
+// stopped or the restart intensity is exceeded.
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return nil
+	}
+	s.restarts = nil
+	for _, c := range s.children {
+		s.run(c)
+	}
+	s.mu.Unlock()
+
+	var pending []exit
+	for {
+		var e exit
+		if len(pending) > 0 {
+			e, pending = pending[0], pending[1:]
+		} else {
+			select {
+			case e = <-s.exits:
+			case <-s.quit:
+				return nil
+			}
+		}
+
+		var err error
+		if pending, err = s.handle(e, pending); err != nil {
+			return err
+		}
+	}
+}
+
+// Stop stops supervising and stops every running child in reverse order. All
+// children are stopped even if some fail; the first error is returned.
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	if !s.stopping && s.quit != nil {
+		close(s.quit)
+	}
+	s.stopping = true
+	running := s.claim(s.children)
+	s.mu.Unlock()
+
+	var first error
+	for _, c := range running {
+		if err := s.stop(c); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Reload reloads every child implementing Reloader.
+func (s *Supervisor) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clock := clockOr(s.Clock)
+	for _, c := range s.children {
+		if r, ok := c.lc.(Reloader); ok {
+			begin := clock.Now()
+			if err := r.Reload(); err != nil {
+				return phaseError(c.name, PhaseReload, 1, clock.Now().Sub(begin), err)
+			}
+		}
+	}
+	return nil
+}
+
+// handle reacts to a child's exit according to the Strategy. Exits of other
+// children observed while waiting for stopped siblings are appended to
+// pending, which is returned.
+func (s *Supervisor) handle(e exit, pending []exit) ([]exit, error) {
+	s.mu.Lock()
+	if e.gen != e.child.gen {
+		s.mu.Unlock()
+		return pending, nil
+	}
+	e.child.running = false
+	attempt := e.child.gen
+	s.mu.Unlock()
+
+	if e.err == nil {
+		msgSupervisedChildExited.emit(
+			log.String("child", e.child.name))
+		return pending, nil
+	}
+
+	msgSupervisedChildFailed.emit(
+		log.String("child", e.child.name),
+		log.String("strategy", s.Strategy.String()),
+		log.String("error", e.err.Error()),
+	)
+	if err := s.stop(e.child); err != nil {
+		msgUnableToStopSupervisedChild.emit(
+			log.String("child", e.child.name),
+			log.String("error", err.Error()),
+		)
+	}
+
+	if !s.allowRestart(clockOr(s.Clock).Now()) {
+		msgRestartIntensityExceeded.emit(
+			log.String("child", e.child.name))
+		s.shutdown(s.children, pending)
+		return nil, phaseError(e.child.name, PhaseStart, attempt, e.elapsed,
+			fmt.Errorf("restart intensity exceeded: %w", e.err))
+	}
+
+	targets := s.targets(e.child)
+	pending = s.shutdown(targets, pending)
+
+	clock := clockOr(s.Clock)
+	for _, c := range targets {
+		s.mu.Lock()
+		restart := !s.stopping && !c.running
+		s.mu.Unlock()
+		if !restart {
+			continue
+		}
+
+		begin := clock.Now()
+		if err := c.lc.Init(); err != nil {
+			s.shutdown(s.children, pending)
+			return nil, phaseError(c.name, PhaseInit, attempt+1, clock.Now().Sub(begin), err)
+		}
+
+		s.mu.Lock()
+		if s.stopping {
+			s.mu.Unlock()
+			return pending, nil
+		}
+		s.run(c)
+		s.mu.Unlock()
+	}
+	return pending, nil
+}
+
+// allowRestart records a restart at now and reports whether it is within the
+// restart intensity.
+func (s *Supervisor) allowRestart(now time.Time) bool {
+	max, period := s.MaxRestarts, s.Period
+	if max == 0 {
+		max = DefaultMaxRestarts
+	}
+	if period == 0 {
+		period = DefaultRestartPeriod
+	}
+
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < period {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = append(recent, now)
+	return len(s.restarts) <= max
+}
+
+// targets returns the children restarted when c fails, in start order.
+func (s *Supervisor) targets(c *child) []*child {
+	switch s.Strategy {
+	case OneForAll:
+		return s.children
+	case RestForOne:
+		for i, sibling := range s.children {
+			if sibling == c {
+				return s.children[i:]
+			}
+		}
+	}
+	return []*child{c}
+}
+
+// shutdown stops the running children among targets in reverse order and
+// waits for their Start to return. Exits of other children received in the
+// meantime are appended to pending, which is returned.
+func (s *Supervisor) shutdown(targets []*child, pending []exit) []exit {
+	waiting := make(map[*child]bool)
+	s.mu.Lock()
+	running := s.claim(targets)
+	s.mu.Unlock()
+
+	for _, c := range running {
+		waiting[c] = true
+		if err := s.stop(c); err != nil {
+			msgUnableToStopSupervisedChild.emit(
+				log.String("child", c.name),
+				log.String("error", err.Error()),
+			)
+		}
+	}
+
+	for len(waiting) > 0 {
+		select {
+		case e := <-s.exits:
+			s.mu.Lock()
+			current := e.gen == e.child.gen
+			if current && !waiting[e.child] {
+				e.child.running = false
+			}
+			s.mu.Unlock()
+
+			if current && waiting[e.child] {
+				delete(waiting, e.child)
+				continue
+			}
+			pending = append(pending, e)
+		case <-s.quit:
+			return pending
+		}
+	}
+	return pending
+}
+
+// run starts a child in its own goroutine. The caller must hold s.mu.
+func (s *Supervisor) run(c *child) {
+	c.running = true
+	c.gen++
+	gen, exits, quit, clock := c.gen, s.exits, s.quit, clockOr(s.Clock)
+	go func() {
+		begin := clock.Now()
+		err := c.lc.Start()
+		e := exit{child: c, gen: gen, err: err, elapsed: clock.Now().Sub(begin)}
+		select {
+		case exits <- e:
+		case <-quit:
+		}
+	}()
+}
+
+// claim marks the running children among targets as no longer running and
+// returns them in reverse order, to be stopped. The caller must hold s.mu.
+func (s *Supervisor) claim(targets []*child) []*child {
+	var running []*child
+	for i := len(targets) - 1; i >= 0; i-- {
+		if c := targets[i]; c.running {
+			c.running = false
+			running = append(running, c)
+		}
+	}
+	return running
+}
+
+// stop stops a child. The caller must not hold s.mu, as a child's Stop may
+// wait for its Start to return.
+func (s *Supervisor) stop(c *child) error {
+	clock := clockOr(s.Clock)
+	begin := clock.Now()
+	if err := c.lc.Stop(); err != nil {
+		return phaseError(c.name, PhaseStop, 1, clock.Now().Sub(begin), err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// DefaultSwapTimeout bounds how long a rebuilt component may take to become
+// ready when no timeout is given to Rebuild.
+const DefaultSwapTimeout = 30 * time.Second
+
+// Rebuild declares a function deciding, on every Reload, whether a running
+// component must be recreated, such as to listen on a new port. If fn returns
+// a Lifecycle, the new instance is initialized and started alongside the old
+// one, and once it is ready, within timeout, it replaces the old instance,
+// which is then stopped, so the component never stops serving. If the new
+// instance is not ready in time, it is stopped, the old instance is kept and
+// the reload fails. If fn returns nil, the component is reloaded as usual.
+//
+// Both instances run at once, so the new one must not depend on resources the
+// old one holds exclusively, such as the same listening port without
+// SO_REUSEPORT.
+func Rebuild(fn func(cfg interface{}) (Lifecycle, error), timeout time.Duration) ComponentOption {
+	return func(c *component) {
+		c.rebuild = fn
+		c.swapTimeout = timeout
+		if c.swapTimeout <= 0 {
+			c.swapTimeout = DefaultSwapTimeout
+		}
+	}
+}
+
+// swap replaces the i-th component, c, with a new instance lc once it is
+// ready. The caller must hold g.mu.
+func (g *Group) swap(i int, c *component, lc Lifecycle) error {
+	next := &component{
+		name:        c.name,
+		lc:          lc,
+		enabled:     c.enabled,
+		restart:     c.restart,
+		backoff:     c.backoff,
+		optional:    c.optional,
+		retry:       c.retry,
+		shard:       c.shard,
+		rebuild:     c.rebuild,
+		swapTimeout: c.swapTimeout,
+	}
+	if err := g.init(next); err != nil {
+		return err
+	}
+
+	if g.started {
+		g.run(next)
+		clock := clockOr(g.Clock)
+		deadline := clock.After(c.swapTimeout)
+		for {
+			err := next.ready()
+			if err == nil {
+				break
+			}
+			select {
+			case <-clock.After(bootReadyPoll):
+				continue
+			case <-deadline:
+			}
+			g.stop(next)
+			return fmt.Errorf("component %s: rebuilt instance not ready within %s: %w",
+				c.name, c.swapTimeout, err)
+		}
+	}
+
+	g.components[i] = next
+	msgComponentSwapped.emit(
+		log.String("component", c.name))
+	return g.stop(c)
+}
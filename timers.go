@@ -0,0 +1,80 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"time"
+)
+
+// Ticker returns a channel delivering ticks every interval, measured on the
+// Dissembler's Clock, that is closed once the lifecycle begins stopping, so a
+// component goroutine ranging over it exits instead of blocking forever on a
+// ticker nobody stopped:
+//
+//	go func() {
+//		for range d.Ticker(time.Minute) {
+//			refresh()
+//		}
+//	}()
+//
+// As with time.Ticker, ticks are dropped for a slow receiver. A tick pending
+// when the lifecycle begins stopping is discarded.
+func (d *Dissembler) Ticker(interval time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	t := clockOr(d.clock).NewTicker(interval)
+	d.spawn("ticker", func(ctx context.Context) error {
+		defer close(c)
+		defer t.Stop()
+		for {
+			select {
+			case now := <-t.C():
+				select {
+				case c <- now:
+				default:
+				}
+			case <-ctx.Done():
+				select {
+				case <-c:
+				default:
+				}
+				return nil
+			}
+		}
+	})
+	return c
+}
+
+// After returns a channel delivering the time once dur has elapsed on the
+// Dissembler's Clock, or closed without delivering it once the lifecycle
+// begins stopping first, so a goroutine waiting on it returns. Receiving the
+// zero time means the lifecycle is stopping.
+func (d *Dissembler) After(dur time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	t := clockOr(d.clock).NewTimer(dur)
+	d.spawn("after", func(ctx context.Context) error {
+		defer close(c)
+		select {
+		case now := <-t.C():
+			c <- now
+		case <-ctx.Done():
+			t.Stop()
+		}
+		return nil
+	})
+	return c
+}
+
+// beginShutdown releases the channels returned by Ticker and After and
+// cancels the goroutines started with Go, in waves if WithStopConcurrency is
+// given.
+func (d *Dissembler) beginShutdown() {
+	if d.shutdown.Err() != nil {
+		return
+	}
+	d.cancelShutdown()
+	if d.stopConcurrency > 0 {
+		go d.stopInWaves()
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+// zone holds the *time.Location pinned by WithTimezone, if any. time.Local is
+// left alone, as other goroutines may be reading it.
+var zone atomic.Value
+
+// localeName matches POSIX locale names, such as "C", "POSIX" or
+// "en_US.UTF-8".
+var localeName = regexp.MustCompile(`^(C|POSIX|[a-z]{2,3}(_[A-Z]{2})?)(\.[A-Za-z0-9-]+)?(@[a-z]+)?$`)
+
+// WithUTC pins the process's local time zone to UTC. See WithTimezone.
+func WithUTC() Option {
+	return WithTimezone("UTC")
+}
+
+// WithTimezone pins the process's local time zone to the named IANA zone,
+// such as "America/Chicago", regardless of the host's configuration. Log
+// timestamps, the times of scheduled stops, Location and child processes all
+// observe the pinned zone. The zone is applied and logged when Serve begins;
+// Serve fails if it is unknown.
+func WithTimezone(tz string) Option {
+	return func(d *Dissembler) {
+		d.timezone = tz
+	}
+}
+
+// WithLocale pins the locale of child processes, such as "C.UTF-8", setting
+// LANG and LC_ALL, so tools the process runs format text, numbers and dates
+// the same way whatever the host's configuration. Go itself is unaffected by
+// the locale. The locale is applied and logged when Serve begins; Serve fails
+// if it is not a valid locale name.
+func WithLocale(locale string) Option {
+	return func(d *Dissembler) {
+		d.locale = locale
+	}
+}
+
+// Location returns the time zone pinned by WithTimezone, or time.Local if
+// none is.
+func Location() *time.Location {
+	if loc, ok := zone.Load().(*time.Location); ok {
+		return loc
+	}
+	return time.Local
+}
+
+// pinTimezone applies the configured time zone and locale, if any.
+func (d *Dissembler) pinTimezone() error {
+	if d.timezone != "" {
+		loc, err := time.LoadLocation(d.timezone)
+		if err != nil {
+			return err
+		}
+		zone.Store(loc)
+		if err := os.Setenv("TZ", d.timezone); err != nil {
+			return err
+		}
+
+		name, offset := time.Now().In(loc).Zone()
+		msgTimezonePinned.emit(
+			log.String("timezone", loc.String()),
+			log.String("zone", name),
+			log.Int("offset", offset),
+		)
+	}
+
+	if d.locale != "" {
+		if !localeName.MatchString(d.locale) {
+			return fmt.Errorf("invalid locale %q", d.locale)
+		}
+		for _, env := range []string{"LANG", "LC_ALL"} {
+			if err := os.Setenv(env, d.locale); err != nil {
+				return err
+			}
+		}
+		msgLocalePinned.emit(
+			log.String("locale", d.locale),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// errTraceParent is returned for a malformed traceparent.
+var errTraceParent = errors.New("malformed traceparent")
+
+// ContextReloader is an optional interface that may be implemented by a
+// Lifecycle in place of Reloader to receive a context with each reload. When
+// the reload was requested through the control socket or admin API with a W3C
+// traceparent, the context carries it; see TraceParent.
+type ContextReloader interface {
+	ReloadContext(ctx context.Context) error
+}
+
+// ContextStopper is an optional interface that may be implemented by a
+// Lifecycle to receive a context when stopped, in place of Stop. When the stop
+// was requested through the control socket with a W3C traceparent, the
+// context carries it; see TraceParent. When stopped by a terminating signal,
+// the context carries the signal, see StopSignal, and expires once any budget
+// set by WithStopBudget is spent.
+type ContextStopper interface {
+	StopContext(ctx context.Context) error
+}
+
+// traceParentKey is the context key of the traceparent.
+type traceParentKey struct{}
+
+// TraceParent returns the W3C traceparent carried by ctx, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", so reloads and
+// stops triggered administratively can be joined to the caller's trace.
+func TraceParent(ctx context.Context) (string, bool) {
+	tp, ok := ctx.Value(traceParentKey{}).(string)
+	return tp, ok
+}
+
+// withTraceParent returns ctx carrying the traceparent tp, which is validated
+// against the W3C Trace Context format. An empty tp returns ctx unchanged.
+func withTraceParent(ctx context.Context, tp string) (context.Context, error) {
+	if tp == "" {
+		return ctx, nil
+	}
+	if !validTraceParent(tp) {
+		return ctx, errTraceParent
+	}
+	return context.WithValue(ctx, traceParentKey{}, tp), nil
+}
+
+// validTraceParent reports whether tp is a W3C traceparent: a version, a
+// trace ID, a parent ID and flags in lowercase hex, separated by dashes, with
+// non-zero IDs. Versions after 00 may append further fields.
+func validTraceParent(tp string) bool {
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 {
+		return false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isLowerHex(version, 2) || version == "ff":
+		return false
+	case version == "00" && len(parts) != 4:
+		return false
+	case !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32):
+		return false
+	case !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16):
+		return false
+	}
+	return isLowerHex(flags, 2)
+}
+
+// isLowerHex reports whether s is n lowercase hex digits.
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Copyright © 2017 Christian R. Vozar ⚜
+// Licensed under BSD 3-Clause "New" or "Revised". All rights reserved.
+
+package dissembler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/uber-go/zap"
+)
+
+const (
+	// envUpgradeFD is the file descriptor on which an upgraded process tells
+	// its parent it is ready.
+	envUpgradeFD = "DISSEMBLER_UPGRADE_FD"
+
+	// UpgradeTimeout bounds how long an upgrade waits for the new process to
+	// become ready before abandoning it.
+	UpgradeTimeout = time.Minute
+)
+
+// ErrUpgradeTimeout is returned when the process started by an upgrade does not
+// become ready within UpgradeTimeout.
+var ErrUpgradeTimeout = errors.New("upgraded process did not become ready in time")
+
+// upgrade starts a new instance of the running executable, handing it every
+// listener opened through Listen and ListenPacket, and waits for it to become
+// ready. Once upgrade returns nil the new process is serving and the caller
+// should stop.
+func (d *Dissembler) upgrade() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	files, names, metas, err := listeners.files()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	env := make([]string, 0, len(os.Environ())+5)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envListeners+"=") && !strings.HasPrefix(kv, envListenerMeta+"=") &&
+			!strings.HasPrefix(kv, envUpgradeFD+"=") && !strings.HasPrefix(kv, envCounters+"=") &&
+			!strings.HasPrefix(kv, envRuntimeDir+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		envListeners+"="+names,
+		envListenerMeta+"="+metas,
+		envCounters+"="+d.encodeCounters(),
+		envUpgradeFD+"="+strconv.Itoa(listenFDStart+len(files)),
+	)
+	if d.ownsRuntimeDir {
+		env = append(env, envRuntimeDir+"="+d.runtimeDir)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	msgUpgrading.emit(
+		log.Int("pid", cmd.Process.Pid),
+		log.Int("listeners", len(files)),
+	)
+
+	// The new process writes a byte once ready, followed by its incarnation
+	// if it counts them.
+	ready := make(chan error, 1)
+	var incarnation uint64
+	go func() {
+		b := make([]byte, 9)
+		n, err := r.Read(b)
+		if err != nil {
+			ready <- fmt.Errorf("upgraded process exited before becoming ready: %w", err)
+			return
+		}
+		if n == len(b) {
+			incarnation = binary.BigEndian.Uint64(b[1:])
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-clockOr(d.clock).After(UpgradeTimeout):
+		err = ErrUpgradeTimeout
+		cmd.Process.Kill()
+	}
+	if err != nil {
+		cmd.Wait()
+		return err
+	}
+
+	listeners.handOff()
+	d.successor = cmd.Process.Pid
+	msgUpgraded.emit(
+		log.Int("pid", cmd.Process.Pid),
+		log.Int64("incarnation", int64(incarnation)),
+	)
+	return cmd.Process.Release()
+}
+
+// notifyParent tells the parent of an upgraded process that it is ready, and
+// its incarnation. It does nothing if the process was not started by an
+// upgrade.
+func (d *Dissembler) notifyParent() {
+	fd, err := strconv.Atoi(os.Getenv(envUpgradeFD))
+	if err != nil {
+		return
+	}
+	os.Unsetenv(envUpgradeFD)
+
+	f := os.NewFile(uintptr(fd), "upgrade")
+	defer f.Close()
+	msg := binary.BigEndian.AppendUint64([]byte{1}, d.incarnation)
+	if _, err := f.Write(msg); err != nil {
+		msgUnableToNotifyParent.emit(
+			log.String("error", err.Error()),
+		)
+	}
+}
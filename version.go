@@ -21,3 +21,11 @@ const (
 	// such as "dev" (in development), "beta", "rc1", etc.
 	VersionPrerelease = "alpha"
 )
+
+// fullVersion returns the version with its pre-release marker, if any.
+func fullVersion() string {
+	if VersionPrerelease != "" {
+		return Version + "-" + VersionPrerelease
+	}
+	return Version
+}